@@ -31,6 +31,7 @@ func (jt *AnyTime) UnmarshalJSON(data []byte) error {
 		gophercloud.RFC3339MilliNoZ,
 		gophercloud.RFC3339ZNoTNoZ,
 		gophercloud.RFC3339ZNoT,
+		time.RFC3339Nano,
 	} {
 		t, err2 := time.Parse(layout, s)
 		if err2 != nil {
@@ -20,6 +20,7 @@ func TestAnyTime(t *testing.T) {
 		{"NoTNoZ", "2023-03-16 18:35:47", gophercloud.RFC3339ZNoTNoZ},
 		{"MilliNoZ", "2023-03-16 18:35:47.845000", RFC3339MilliNoTNoZ},
 		{"Micros", "2023-03-16 18:35:47.845000+00:00", RFC3339MilliNoT},
+		{"Offset", "2023-03-16T18:35:47+00:00", time.RFC3339},
 	}
 
 	for _, tc := range testCases {
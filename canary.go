@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack"
+	"github.com/gophercloud/gophercloud/v2/openstack/blockstorage/v3/volumes"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/volumeattach"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/subnets"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"go.uber.org/multierr"
+)
+
+type CanaryOpts struct {
+	Tag        string
+	ImageRef   string
+	FlavorRef  string
+	NetworkID  string
+	CIDR       string
+	VolumeSize int
+}
+
+type CanaryStep struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// Canary creates and deletes resources tagged by the Tag to verify that users are able to boot instances.
+type Canary struct {
+	CanaryOpts
+	Steps []CanaryStep
+
+	compute *gophercloud.ServiceClient
+	network *gophercloud.ServiceClient
+	volume  *gophercloud.ServiceClient
+}
+
+func NewCanary(pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, opts CanaryOpts) (*Canary, error) {
+	c := &Canary{CanaryOpts: opts}
+
+	var err error
+	c.compute, err = openstack.NewComputeV2(pc, eo)
+	if err != nil {
+		return nil, err
+	}
+	// server tags on create
+	c.compute.Microversion = "2.52"
+
+	c.network, err = openstack.NewNetworkV2(pc, eo)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.VolumeSize > 0 {
+		c.volume, err = openstack.NewBlockStorageV3(pc, eo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Canary) step(name string, f func() error) error {
+	start := time.Now()
+	err := f()
+	c.Steps = append(c.Steps, CanaryStep{Name: name, Duration: time.Since(start), Err: err})
+	return err
+}
+
+// Run creates network, port, server and volume, then deletes them in reverse order.
+//
+// Resources left on error are expected to be removed by Cleanup.
+func (c *Canary) Run(ctx context.Context) error {
+	name := c.Tag
+	netID := c.NetworkID
+
+	if netID == "" {
+		err := c.step("network create", func() error {
+			nw, err := networks.Create(ctx, c.network, taggedNetworkOpts{networks.CreateOpts{Name: name}, []string{c.Tag}}).Extract()
+			if err != nil {
+				return err
+			}
+			netID = nw.ID
+
+			noGateway := ""
+			noDHCP := false
+			_, err = subnets.Create(ctx, c.network, subnets.CreateOpts{
+				NetworkID:  nw.ID,
+				Name:       name,
+				CIDR:       c.CIDR,
+				IPVersion:  gophercloud.IPv4,
+				GatewayIP:  &noGateway,
+				EnableDHCP: &noDHCP,
+			}).Extract()
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var portID string
+	err := c.step("port create", func() error {
+		p, err := ports.Create(ctx, c.network, taggedPortOpts{ports.CreateOpts{NetworkID: netID, Name: name}, []string{c.Tag}}).Extract()
+		if err != nil {
+			return err
+		}
+		portID = p.ID
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var serverID string
+	err = c.step("server boot", func() error {
+		srv, err := servers.Create(ctx, c.compute, servers.CreateOpts{
+			Name:      name,
+			ImageRef:  c.ImageRef,
+			FlavorRef: c.FlavorRef,
+			Networks:  []servers.Network{{Port: portID}},
+			Tags:      []string{c.Tag},
+		}, nil).Extract()
+		if err != nil {
+			return err
+		}
+		serverID = srv.ID
+
+		return c.waitServer(ctx, srv.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.VolumeSize > 0 {
+		var volumeID string
+		err = c.step("volume create", func() error {
+			vol, err := volumes.Create(ctx, c.volume, volumes.CreateOpts{
+				Name:     name,
+				Size:     c.VolumeSize,
+				Metadata: map[string]string{c.Tag: "true"},
+			}, nil).Extract()
+			if err != nil {
+				return err
+			}
+			volumeID = vol.ID
+
+			return c.waitVolume(ctx, vol.ID, "available")
+		})
+		if err != nil {
+			return err
+		}
+
+		err = c.step("volume attach", func() error {
+			_, err := volumeattach.Create(ctx, c.compute, serverID, volumeattach.CreateOpts{VolumeID: volumeID}).Extract()
+			if err != nil {
+				return err
+			}
+
+			return c.waitVolume(ctx, volumeID, "in-use")
+		})
+		if err != nil {
+			return err
+		}
+
+		err = c.step("volume detach", func() error {
+			err := volumeattach.Delete(ctx, c.compute, serverID, volumeID).ExtractErr()
+			if err != nil {
+				return err
+			}
+
+			return c.waitVolume(ctx, volumeID, "available")
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Cleanup deletes all resources tagged by the Tag, including leftovers of the previous runs.
+func (c *Canary) Cleanup(ctx context.Context) (int, error) {
+	var found int
+	var errs error
+
+	pages, err := servers.List(c.compute, servers.ListOpts{Tags: c.Tag}).AllPages(ctx)
+	if err != nil {
+		return found, fmt.Errorf("server list: %w", err)
+	}
+
+	srvs, err := servers.ExtractServers(pages)
+	if err != nil {
+		return found, fmt.Errorf("server list: %w", err)
+	}
+
+	for _, srv := range srvs {
+		found++
+		err := servers.Delete(ctx, c.compute, srv.ID).ExtractErr()
+		if err == nil {
+			err = c.waitServerDeleted(ctx, srv.ID)
+		}
+		multierr.AppendInto(&errs, ignoreNotFound(err))
+	}
+
+	if c.volume != nil {
+		pages, err := volumes.List(c.volume, volumes.ListOpts{Metadata: map[string]string{c.Tag: "true"}}).AllPages(ctx)
+		if err != nil {
+			return found, multierr.Append(errs, fmt.Errorf("volume list: %w", err))
+		}
+
+		vols, err := volumes.ExtractVolumes(pages)
+		if err != nil {
+			return found, multierr.Append(errs, fmt.Errorf("volume list: %w", err))
+		}
+
+		for _, vol := range vols {
+			found++
+			err := volumes.Delete(ctx, c.volume, vol.ID, volumes.DeleteOpts{}).ExtractErr()
+			if err == nil {
+				err = c.waitVolumeDeleted(ctx, vol.ID)
+			}
+			multierr.AppendInto(&errs, ignoreNotFound(err))
+		}
+	}
+
+	pages, err = ports.List(c.network, ports.ListOpts{Tags: c.Tag}).AllPages(ctx)
+	if err != nil {
+		return found, multierr.Append(errs, fmt.Errorf("port list: %w", err))
+	}
+
+	prts, err := ports.ExtractPorts(pages)
+	if err != nil {
+		return found, multierr.Append(errs, fmt.Errorf("port list: %w", err))
+	}
+
+	for _, p := range prts {
+		found++
+		err := ports.Delete(ctx, c.network, p.ID).ExtractErr()
+		multierr.AppendInto(&errs, ignoreNotFound(err))
+	}
+
+	pages, err = networks.List(c.network, networks.ListOpts{Tags: c.Tag}).AllPages(ctx)
+	if err != nil {
+		return found, multierr.Append(errs, fmt.Errorf("network list: %w", err))
+	}
+
+	nets, err := networks.ExtractNetworks(pages)
+	if err != nil {
+		return found, multierr.Append(errs, fmt.Errorf("network list: %w", err))
+	}
+
+	for _, n := range nets {
+		found++
+		err := networks.Delete(ctx, c.network, n.ID).ExtractErr()
+		multierr.AppendInto(&errs, ignoreNotFound(err))
+	}
+
+	return found, errs
+}
+
+// taggedNetworkOpts adds tags to the network create request, as networks.CreateOpts has no tags field.
+type taggedNetworkOpts struct {
+	networks.CreateOpts
+	Tags []string
+}
+
+func (o taggedNetworkOpts) ToNetworkCreateMap() (map[string]any, error) {
+	b, err := o.CreateOpts.ToNetworkCreateMap()
+	if err != nil {
+		return nil, err
+	}
+
+	b["network"].(map[string]any)["tags"] = o.Tags
+	return b, nil
+}
+
+// taggedPortOpts tags the port in the create request, so the port left by a failed server boot
+// is deleted by the next run Cleanup.
+type taggedPortOpts struct {
+	ports.CreateOpts
+	Tags []string
+}
+
+func (o taggedPortOpts) ToPortCreateMap() (map[string]any, error) {
+	b, err := o.CreateOpts.ToPortCreateMap()
+	if err != nil {
+		return nil, err
+	}
+
+	b["port"].(map[string]any)["tags"] = o.Tags
+	return b, nil
+}
+
+// State evaluates the step error and duration.
+func (st CanaryStep) State(warning, critical time.Duration) (int, string) {
+	switch {
+	case st.Err != nil:
+		return sensu.CheckStateCritical, "step failed"
+	case st.Duration >= critical:
+		return sensu.CheckStateCritical, "step duration over critical threshold"
+	case st.Duration >= warning:
+		return sensu.CheckStateWarning, "step duration over warning threshold"
+	default:
+		return sensu.CheckStateOK, ""
+	}
+}
+
+func (c *Canary) waitServer(ctx context.Context, id string) error {
+	return gophercloud.WaitFor(ctx, func(ctx context.Context) (bool, error) {
+		srv, err := servers.Get(ctx, c.compute, id).Extract()
+		if err != nil {
+			return false, err
+		}
+
+		switch srv.Status {
+		case "ACTIVE":
+			return true, nil
+		case "ERROR":
+			return false, fmt.Errorf("server %s in ERROR state: %s", id, srv.Fault.Message)
+		default:
+			return false, nil
+		}
+	})
+}
+
+func (c *Canary) waitServerDeleted(ctx context.Context, id string) error {
+	return gophercloud.WaitFor(ctx, func(ctx context.Context) (bool, error) {
+		_, err := servers.Get(ctx, c.compute, id).Extract()
+		if gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+func (c *Canary) waitVolume(ctx context.Context, id, status string) error {
+	return gophercloud.WaitFor(ctx, func(ctx context.Context) (bool, error) {
+		vol, err := volumes.Get(ctx, c.volume, id).Extract()
+		if err != nil {
+			return false, err
+		}
+
+		switch vol.Status {
+		case status:
+			return true, nil
+		case "error", "error_attaching", "error_detaching":
+			return false, fmt.Errorf("volume %s in %s state", id, vol.Status)
+		default:
+			return false, nil
+		}
+	})
+}
+
+func (c *Canary) waitVolumeDeleted(ctx context.Context, id string) error {
+	return gophercloud.WaitFor(ctx, func(ctx context.Context) (bool, error) {
+		vol, err := volumes.Get(ctx, c.volume, id).Extract()
+		if gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if vol.Status == "error_deleting" {
+			return false, fmt.Errorf("volume %s in %s state", id, vol.Status)
+		}
+		return false, nil
+	})
+}
+
+func ignoreNotFound(err error) error {
+	if gophercloud.ResponseCodeIs(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanaryCleanup(t *testing.T) {
+	var mu sync.Mutex
+	deleted := make([]string, 0)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /compute/servers/detail", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "sensu-canary", r.URL.Query().Get("tags"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"servers": [{"id": "s1"}]}`)
+	})
+	mux.HandleFunc("GET /compute/servers/s1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	volumeGets := 0
+	mux.HandleFunc("GET /volume/volumes/detail", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"volumes": [{"id": "v1", "status": "available"}]}`)
+	})
+	mux.HandleFunc("GET /volume/volumes/v1", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		volumeGets++
+		gone := volumeGets > 1
+		mu.Unlock()
+
+		if gone {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"volume": {"id": "v1", "status": "deleting"}}`)
+	})
+	mux.HandleFunc("GET /network/v2.0/ports", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "sensu-canary", r.URL.Query().Get("tags"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ports": [{"id": "p1"}, {"id": "p2"}]}`)
+	})
+	mux.HandleFunc("GET /network/v2.0/networks", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "sensu-canary", r.URL.Query().Get("tags"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"networks": [{"id": "n1"}]}`)
+	})
+	mux.HandleFunc("DELETE /", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		deleted = append(deleted, r.URL.Path)
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/network/v2.0/ports/p2":
+			// already deleted with the server
+			w.WriteHeader(http.StatusNotFound)
+		case "/network/v2.0/networks/n1":
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	pc := &gophercloud.ProviderClient{TokenID: "token"}
+	c := &Canary{
+		CanaryOpts: CanaryOpts{Tag: "sensu-canary"},
+		compute:    &gophercloud.ServiceClient{ProviderClient: pc, Endpoint: srv.URL + "/compute/"},
+		network:    &gophercloud.ServiceClient{ProviderClient: pc, Endpoint: srv.URL + "/network/", ResourceBase: srv.URL + "/network/v2.0/"},
+		volume:     &gophercloud.ServiceClient{ProviderClient: pc, Endpoint: srv.URL + "/volume/"},
+	}
+
+	found, err := c.Cleanup(context.Background())
+	assert.Equal(t, 5, found)
+	assert.Equal(t, 2, volumeGets, "waited for the volume deletion")
+	assert.Error(t, err)
+	assert.True(t, gophercloud.ResponseCodeIs(err, http.StatusConflict))
+	assert.Equal(t, []string{
+		"/compute/servers/s1",
+		"/volume/volumes/v1",
+		"/network/v2.0/ports/p1",
+		"/network/v2.0/ports/p2",
+		"/network/v2.0/networks/n1",
+	}, deleted)
+}
+
+func TestCanaryStep(t *testing.T) {
+	c := &Canary{}
+	stepErr := errors.New("boom")
+
+	err := c.step("slow", func() error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = c.step("failed", func() error { return stepErr })
+	assert.Equal(t, stepErr, err)
+
+	require.Len(t, c.Steps, 2)
+	assert.Equal(t, "slow", c.Steps[0].Name)
+	assert.GreaterOrEqual(t, c.Steps[0].Duration, 20*time.Millisecond)
+	assert.NoError(t, c.Steps[0].Err)
+	assert.Equal(t, "failed", c.Steps[1].Name)
+	assert.Equal(t, stepErr, c.Steps[1].Err)
+}
+
+func TestCanaryStepState(t *testing.T) {
+	testCases := []struct {
+		name   string
+		step   CanaryStep
+		state  int
+		reason string
+	}{
+		{"fast", CanaryStep{Duration: 10 * time.Second}, sensu.CheckStateOK, ""},
+		{"warning", CanaryStep{Duration: time.Minute}, sensu.CheckStateWarning, "step duration over warning threshold"},
+		{"critical", CanaryStep{Duration: 4 * time.Minute}, sensu.CheckStateCritical, "step duration over critical threshold"},
+		{"error", CanaryStep{Duration: time.Second, Err: errors.New("boom")}, sensu.CheckStateCritical, "step failed"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state, reason := tc.step.State(time.Minute, 3*time.Minute)
+			assert.Equal(t, tc.state, state)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
+func TestCanaryTaggedOpts(t *testing.T) {
+	b, err := taggedPortOpts{ports.CreateOpts{NetworkID: "n1", Name: "sensu-canary"}, []string{"sensu-canary"}}.ToPortCreateMap()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"port": map[string]any{"network_id": "n1", "name": "sensu-canary", "tags": []string{"sensu-canary"}}}, b)
+
+	b, err = taggedNetworkOpts{networks.CreateOpts{Name: "sensu-canary"}, []string{"sensu-canary"}}.ToNetworkCreateMap()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"network": map[string]any{"name": "sensu-canary", "tags": []string{"sensu-canary"}}}, b)
+}
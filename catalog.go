@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/identity/v3/tokens"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+)
+
+// ServiceCatalog returns the catalog received with the provider client token.
+func ServiceCatalog(pc *gophercloud.ProviderClient) (*tokens.ServiceCatalog, error) {
+	switch r := pc.GetAuthResult().(type) {
+	case tokens.CreateResult:
+		return r.ExtractServiceCatalog()
+	case tokens.GetResult:
+		return r.ExtractServiceCatalog()
+	default:
+		return nil, fmt.Errorf("unsupported auth result: %T", r)
+	}
+}
+
+// catalogEndpoints returns endpoints of the service type, region is ignored if empty.
+func catalogEndpoints(catalog *tokens.ServiceCatalog, serviceType, region string) []tokens.Endpoint {
+	ret := make([]tokens.Endpoint, 0)
+	for _, entry := range catalog.Entries {
+		if entry.Type != serviceType {
+			continue
+		}
+
+		for _, ep := range entry.Endpoints {
+			if endpointInRegion(ep, region) {
+				ret = append(ret, ep)
+			}
+		}
+	}
+
+	return ret
+}
+
+// endpointInRegion reports if endpoint belongs to the region, empty region matches any.
+func endpointInRegion(ep tokens.Endpoint, region string) bool {
+	return region == "" || ep.Region == region || ep.RegionID == region
+}
+
+type CatalogProblem struct {
+	Service   string
+	Region    string
+	Interface string
+	URL       string
+	Problem   string
+	State     int
+}
+
+// catalogRegions returns sorted list of regions used by the catalog endpoints.
+func catalogRegions(catalog *tokens.ServiceCatalog) []string {
+	ret := make([]string, 0)
+	for _, entry := range catalog.Entries {
+		for _, ep := range entry.Endpoints {
+			if !slices.Contains(ret, ep.Region) {
+				ret = append(ret, ep.Region)
+			}
+		}
+	}
+
+	sort.Strings(ret)
+	return ret
+}
+
+// validateCatalog checks that each service has endpoints of all interfaces in all regions,
+// endpoint URLs are valid and not duplicated.
+func validateCatalog(catalog *tokens.ServiceCatalog, regions, interfaces []string) []CatalogProblem {
+	ret := make([]CatalogProblem, 0)
+	urlOwners := make(map[string][]string)
+
+	for _, entry := range catalog.Entries {
+		seen := make(map[string]int)
+
+		for _, ep := range entry.Endpoints {
+			seen[ep.Region+"/"+ep.Interface]++
+
+			u, err := url.Parse(ep.URL)
+			switch {
+			case err != nil:
+				ret = append(ret, CatalogProblem{entry.Type, ep.Region, ep.Interface, ep.URL, err.Error(), sensu.CheckStateCritical})
+				continue
+
+			case u.Scheme != "http" && u.Scheme != "https":
+				ret = append(ret, CatalogProblem{entry.Type, ep.Region, ep.Interface, ep.URL, "unsupported scheme: " + u.Scheme, sensu.CheckStateCritical})
+				continue
+
+			case u.Hostname() == "":
+				ret = append(ret, CatalogProblem{entry.Type, ep.Region, ep.Interface, ep.URL, "no host", sensu.CheckStateCritical})
+				continue
+			}
+
+			if !slices.Contains(urlOwners[ep.URL], entry.Type) {
+				urlOwners[ep.URL] = append(urlOwners[ep.URL], entry.Type)
+			}
+		}
+
+		for _, region := range regions {
+			for _, iface := range interfaces {
+				switch n := seen[region+"/"+iface]; {
+				case n == 0:
+					ret = append(ret, CatalogProblem{entry.Type, region, iface, "", "missing endpoint", sensu.CheckStateCritical})
+				case n > 1:
+					ret = append(ret, CatalogProblem{entry.Type, region, iface, "", fmt.Sprintf("%d endpoints", n), sensu.CheckStateWarning})
+				}
+			}
+		}
+	}
+
+	for u, owners := range urlOwners {
+		if len(owners) > 1 {
+			sort.Strings(owners)
+			ret = append(ret, CatalogProblem{strings.Join(owners, " "), "", "", u, "URL shared by services", sensu.CheckStateWarning})
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		pi, pj := ret[i], ret[j]
+		if pi.Service != pj.Service {
+			return pi.Service < pj.Service
+		}
+		if pi.Region != pj.Region {
+			return pi.Region < pj.Region
+		}
+		if pi.Interface != pj.Interface {
+			return pi.Interface < pj.Interface
+		}
+		return pi.URL < pj.URL
+	})
+
+	return ret
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/identity/v3/tokens"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCatalog(t *testing.T) {
+	catalog := &tokens.ServiceCatalog{
+		Entries: []tokens.CatalogEntry{
+			{Type: "compute", Endpoints: []tokens.Endpoint{
+				{Region: "r1", Interface: "public", URL: "https://api.example.com:8774/v2.1"},
+				{Region: "r1", Interface: "internal", URL: "http://nova.internal:8774/v2.1"},
+			}},
+			{Type: "volumev3", Endpoints: []tokens.Endpoint{
+				{Region: "r1", Interface: "public", URL: "https://api.example.com:8774/v2.1"},
+				{Region: "r1", Interface: "internal", URL: "cinder.internal:8776"},
+				{Region: "r1", Interface: "internal", URL: "http://cinder.internal:8776/v3"},
+			}},
+		},
+	}
+
+	assert.Equal(t, []string{"r1"}, catalogRegions(catalog))
+
+	obtained := validateCatalog(catalog, []string{"r1"}, []string{"public", "internal", "admin"})
+	assert.Equal(t, []CatalogProblem{
+		{"compute", "r1", "admin", "", "missing endpoint", sensu.CheckStateCritical},
+		{"compute volumev3", "", "", "https://api.example.com:8774/v2.1", "URL shared by services", sensu.CheckStateWarning},
+		{"volumev3", "r1", "admin", "", "missing endpoint", sensu.CheckStateCritical},
+		{"volumev3", "r1", "internal", "", "2 endpoints", sensu.CheckStateWarning},
+		{"volumev3", "r1", "internal", "cinder.internal:8776", "unsupported scheme: cinder.internal", sensu.CheckStateCritical},
+	}, obtained)
+}
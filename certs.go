@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+)
+
+type CertInfo struct {
+	Subject     string
+	Issuer      string
+	NotAfter    time.Time
+	ChainErr    error
+	HostnameErr error
+}
+
+// DaysLeft returns number of whole days before the certificate expires.
+func (ci CertInfo) DaysLeft(now time.Time) int {
+	return int(ci.NotAfter.Sub(now).Hours() / 24)
+}
+
+// inspectCertificate connects to addr and verifies presented certificate chain against roots of the base config.
+func inspectCertificate(ctx context.Context, addr, serverName string, base *tls.Config) (CertInfo, error) {
+	var ci CertInfo
+
+	cfg := &tls.Config{
+		ServerName: serverName,
+		// verification is done below to report chain and hostname problems separately
+		InsecureSkipVerify: true,
+	}
+	if base != nil {
+		cfg.RootCAs = base.RootCAs
+		cfg.Certificates = base.Certificates
+	}
+
+	d := tls.Dialer{Config: cfg, NetDialer: &net.Dialer{}}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return ci, err
+	}
+	defer conn.Close()
+
+	peers := conn.(*tls.Conn).ConnectionState().PeerCertificates
+	if len(peers) == 0 {
+		return ci, fmt.Errorf("no certificates presented")
+	}
+
+	leaf := peers[0]
+	ci.Subject = leaf.Subject.String()
+	ci.Issuer = leaf.Issuer.String()
+	ci.NotAfter = leaf.NotAfter
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range peers[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, ci.ChainErr = leaf.Verify(x509.VerifyOptions{
+		Roots:         cfg.RootCAs,
+		Intermediates: intermediates,
+	})
+	ci.HostnameErr = leaf.VerifyHostname(serverName)
+
+	return ci, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	ctx := context.Background()
+
+	t.Run("untrusted", func(t *testing.T) {
+		ci, err := inspectCertificate(ctx, u.Host, "example.com", nil)
+		require.NoError(t, err)
+		assert.Error(t, ci.ChainErr)
+		assert.NoError(t, ci.HostnameErr)
+		assert.Greater(t, ci.DaysLeft(time.Now()), 0)
+	})
+
+	t.Run("trusted", func(t *testing.T) {
+		ci, err := inspectCertificate(ctx, u.Host, "example.com", &tls.Config{RootCAs: roots})
+		require.NoError(t, err)
+		assert.NoError(t, ci.ChainErr)
+		assert.NoError(t, ci.HostnameErr)
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		ci, err := inspectCertificate(ctx, u.Host, "api.example.org", &tls.Config{RootCAs: roots})
+		require.NoError(t, err)
+		assert.NoError(t, ci.ChainErr)
+		assert.Error(t, ci.HostnameErr)
+	})
+}
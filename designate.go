@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/dns/v2/zones"
+	"github.com/gophercloud/gophercloud/v2/pagination"
+)
+
+type DesignateServiceStatus struct {
+	ID            string  `json:"id"`
+	Hostname      string  `json:"hostname"`
+	ServiceName   string  `json:"service_name"`
+	Status        string  `json:"status"`
+	HeartbeatedAt AnyTime `json:"heartbeated_at"`
+	CreatedAt     AnyTime `json:"created_at"`
+	UpdatedAt     AnyTime `json:"updated_at"`
+}
+
+type DesignateServiceStatusPage struct {
+	pagination.SinglePageBase
+}
+
+func (page DesignateServiceStatusPage) IsEmpty() (bool, error) {
+	if page.StatusCode == 204 {
+		return true, nil
+	}
+
+	services, err := ExtractDesignateServiceStatuses(page)
+	return len(services) == 0, err
+}
+
+func ExtractDesignateServiceStatuses(r pagination.Page) ([]DesignateServiceStatus, error) {
+	var s struct {
+		ServiceStatuses []DesignateServiceStatus `json:"service_statuses"`
+	}
+	err := (r.(DesignateServiceStatusPage)).ExtractInto(&s)
+	return s.ServiceStatuses, err
+}
+
+func DesignateServiceStatusList(client *gophercloud.ServiceClient) pagination.Pager {
+	url := client.ServiceURL("service_statuses")
+
+	return pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
+		return DesignateServiceStatusPage{pagination.SinglePageBase(r)}
+	})
+}
+
+// zoneStuck reports if zone remains PENDING or ERROR since before the deadline.
+func zoneStuck(z zones.Zone, deadline time.Time) bool {
+	if z.Status != "PENDING" && z.Status != "ERROR" {
+		return false
+	}
+
+	changed := z.UpdatedAt
+	if changed.IsZero() {
+		changed = z.CreatedAt
+	}
+
+	return changed.Before(deadline)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/dns/v2/zones"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDesignateServiceStatusList(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /service_statuses", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"service_statuses": [{"id": "s1", "hostname": "ctl-1", "service_name": "central", "status": "UP",
+			"heartbeated_at": "2024-05-01T10:00:00.000000", "created_at": "2024-01-01T00:00:00.000000", "updated_at": null}]}`)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cli := &gophercloud.ServiceClient{ProviderClient: &gophercloud.ProviderClient{TokenID: "token"}, Endpoint: srv.URL + "/"}
+
+	pages, err := DesignateServiceStatusList(cli).AllPages(context.Background())
+	require.NoError(t, err)
+
+	srvs, err := ExtractDesignateServiceStatuses(pages)
+	require.NoError(t, err)
+	require.Len(t, srvs, 1)
+	assert.Equal(t, "central", srvs[0].ServiceName)
+	assert.Equal(t, "UP", srvs[0].Status)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), srvs[0].HeartbeatedAt.As())
+	assert.True(t, srvs[0].UpdatedAt.As().IsZero())
+}
+
+func TestZoneStuck(t *testing.T) {
+	now := time.Now()
+	deadline := now.Add(-10 * time.Minute)
+	old := now.Add(-time.Hour)
+
+	testCases := []struct {
+		name     string
+		zone     zones.Zone
+		expected bool
+	}{
+		{"active", zones.Zone{Status: "ACTIVE", UpdatedAt: old}, false},
+		{"pending", zones.Zone{Status: "PENDING", UpdatedAt: now}, false},
+		{"pending-old", zones.Zone{Status: "PENDING", UpdatedAt: old}, true},
+		{"error-old", zones.Zone{Status: "ERROR", UpdatedAt: old}, true},
+		{"error-recent", zones.Zone{Status: "ERROR", CreatedAt: old, UpdatedAt: now}, false},
+		{"never-updated", zones.Zone{Status: "PENDING", CreatedAt: old}, true},
+		{"just-created", zones.Zone{Status: "PENDING", CreatedAt: now}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, zoneStuck(tc.zone, deadline))
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// ServiceDriver describes "services" style API endpoint which returns list of service records.
+//
+// Paths (ListPath and Fields) are dot separated keys of the response JSON document.
+type ServiceDriver struct {
+	CatalogType        string              `yaml:"catalog_type"`
+	Path               string              `yaml:"path"`
+	Microversion       string              `yaml:"microversion"`
+	MicroversionHeader string              `yaml:"microversion_header"`
+	ListPath           string              `yaml:"list_path"`
+	Fields             ServiceDriverFields `yaml:"fields"`
+	UpValues           []string            `yaml:"up_values"`
+	EnabledValues      []string            `yaml:"enabled_values"`
+}
+
+type ServiceDriverFields struct {
+	ID        string `yaml:"id"`
+	Host      string `yaml:"host"`
+	Binary    string `yaml:"binary"`
+	Zone      string `yaml:"zone"`
+	State     string `yaml:"state"`
+	Status    string `yaml:"status"`
+	Disabled  string `yaml:"disabled"`
+	Updated   string `yaml:"updated"`
+	Heartbeat string `yaml:"heartbeat"`
+	Reason    string `yaml:"reason"`
+}
+
+// ServiceRecord is a service entry converted by the ServiceDriver.
+type ServiceRecord struct {
+	ID        string
+	Host      string
+	Binary    string
+	Zone      string
+	State     string
+	Status    string
+	Disabled  bool
+	UpdatedAt time.Time
+	Heartbeat time.Time
+	Reason    string
+}
+
+var builtinServiceDrivers = map[string]ServiceDriver{
+	"orchestration": {
+		CatalogType: "orchestration",
+		Path:        "services",
+		ListPath:    "services",
+		Fields: ServiceDriverFields{
+			ID:      "id",
+			Host:    "host",
+			Binary:  "binary",
+			State:   "status",
+			Updated: "updated_at",
+		},
+	},
+	"container": {
+		CatalogType: "container",
+		Path:        "services",
+		ListPath:    "services",
+		Fields: ServiceDriverFields{
+			ID:        "id",
+			Host:      "host",
+			Binary:    "binary",
+			Zone:      "availability_zone",
+			State:     "state",
+			Disabled:  "disabled",
+			Updated:   "updated_at",
+			Heartbeat: "last_seen_up",
+			Reason:    "disable_reason",
+		},
+	},
+	// Senlin dropped from 2024.1 (Caracal), so gophercloud does not provide clustering client anymore.
+	"clustering": {
+		CatalogType:  "clustering",
+		Path:         "v1/services",
+		Microversion: "1.7",
+		ListPath:     "services",
+		Fields: ServiceDriverFields{
+			ID:      "id",
+			Host:    "host",
+			Binary:  "binary",
+			State:   "state",
+			Status:  "status",
+			Updated: "updated_at",
+			Reason:  "disable_reason",
+		},
+	},
+}
+
+// loadServiceDrivers returns builtin drivers merged with drivers defined in the YAML file.
+func loadServiceDrivers(path string) (map[string]ServiceDriver, error) {
+	drivers := make(map[string]ServiceDriver, len(builtinServiceDrivers))
+	for name, d := range builtinServiceDrivers {
+		drivers[name] = d
+	}
+
+	if path == "" {
+		return drivers, nil
+	}
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var custom map[string]ServiceDriver
+	err = yaml.Unmarshal(buf, &custom)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	for name, d := range custom {
+		if _, ok := builtinServiceDrivers[name]; ok || slices.Contains(builtinServices, name) {
+			return nil, fmt.Errorf("%s: driver %s: name of builtin service", path, name)
+		}
+
+		if d.CatalogType == "" || d.Path == "" || d.Fields.State == "" {
+			return nil, fmt.Errorf("%s: driver %s: catalog_type, path and fields.state are required", path, name)
+		}
+
+		drivers[name] = d
+	}
+
+	return drivers, nil
+}
+
+// NewServiceClient creates a ServiceClient for the catalog type without API specific settings.
+func NewServiceClient(client *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, clientType string) (*gophercloud.ServiceClient, error) {
+	sc := new(gophercloud.ServiceClient)
+	eo.ApplyDefaults(clientType)
+
+	url, err := client.EndpointLocator(eo)
+	if err != nil {
+		return sc, err
+	}
+
+	sc.ProviderClient = client
+	sc.Endpoint = url
+	sc.Type = clientType
+
+	return sc, nil
+}
+
+// List requests service list from the API.
+func (d ServiceDriver) List(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) ([]ServiceRecord, error) {
+	cli, err := NewServiceClient(pc, eo, d.CatalogType)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.MicroversionHeader != "" && d.Microversion != "" {
+		cli.MoreHeaders = map[string]string{d.MicroversionHeader: d.microversionValue()}
+	} else {
+		cli.Microversion = d.Microversion
+	}
+
+	var body any
+	_, err = cli.Get(ctx, cli.ServiceURL(d.Path), &body, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Records(body)
+}
+
+// microversionValue returns the value of the custom microversion header,
+// OpenStack-API-Version expects "<service-type> <version>", service specific headers only the version.
+func (d ServiceDriver) microversionValue() string {
+	if strings.EqualFold(d.MicroversionHeader, "OpenStack-API-Version") {
+		return d.CatalogType + " " + d.Microversion
+	}
+	return d.Microversion
+}
+
+// Records converts decoded JSON response into service records.
+func (d ServiceDriver) Records(body any) ([]ServiceRecord, error) {
+	list, ok := jsonPath(body, d.ListPath).([]any)
+	if !ok {
+		return nil, fmt.Errorf("list not found at %q", d.ListPath)
+	}
+
+	ret := make([]ServiceRecord, 0, len(list))
+	for _, item := range list {
+		var err error
+		rec := ServiceRecord{
+			ID:     jsonString(item, d.Fields.ID),
+			Host:   jsonString(item, d.Fields.Host),
+			Binary: jsonString(item, d.Fields.Binary),
+			Zone:   jsonString(item, d.Fields.Zone),
+			State:  jsonString(item, d.Fields.State),
+			Status: jsonString(item, d.Fields.Status),
+			Reason: jsonString(item, d.Fields.Reason),
+		}
+
+		if d.Fields.Disabled != "" {
+			rec.Disabled, _ = strconv.ParseBool(jsonString(item, d.Fields.Disabled))
+		}
+
+		rec.UpdatedAt, err = jsonTime(item, d.Fields.Updated)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.Fields.Updated, err)
+		}
+
+		rec.Heartbeat, err = jsonTime(item, d.Fields.Heartbeat)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.Fields.Heartbeat, err)
+		}
+
+		ret = append(ret, rec)
+	}
+
+	return ret, nil
+}
+
+// Enabled reports if service record is not disabled by operator.
+func (d ServiceDriver) Enabled(rec ServiceRecord) bool {
+	if d.Fields.Disabled != "" && rec.Disabled {
+		return false
+	}
+
+	if d.Fields.Status != "" {
+		enabledValues := d.EnabledValues
+		if len(enabledValues) == 0 {
+			enabledValues = []string{"enabled"}
+		}
+
+		return slices.Contains(enabledValues, rec.Status)
+	}
+
+	return true
+}
+
+// Up reports if service record state is alive.
+func (d ServiceDriver) Up(rec ServiceRecord) bool {
+	upValues := d.UpValues
+	if len(upValues) == 0 {
+		upValues = []string{"up"}
+	}
+
+	return slices.Contains(upValues, rec.State)
+}
+
+func jsonPath(v any, path string) any {
+	if path == "" {
+		return v
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+
+	return v
+}
+
+func jsonString(v any, path string) string {
+	if path == "" {
+		return ""
+	}
+
+	switch val := jsonPath(v, path).(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func jsonTime(v any, path string) (time.Time, error) {
+	s := jsonString(v, path)
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var t AnyTime
+	err = t.UnmarshalJSON(b)
+	return t.As(), err
+}
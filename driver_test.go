@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceDriverRecords(t *testing.T) {
+	testCases := []struct {
+		name     string
+		driver   string
+		body     string
+		expected []bool
+	}{
+		{"heat", "orchestration", `{"services": [
+			{"id": "a", "binary": "heat-engine", "host": "ctl1", "status": "up", "updated_at": "2023-03-16T18:35:47.000000"},
+			{"id": "b", "binary": "heat-engine", "host": "ctl2", "status": "down", "updated_at": "2023-03-16T18:35:47.000000"}
+		]}`, []bool{true, false}},
+		{"zun", "container", `{"services": [
+			{"id": 1, "binary": "zun-compute", "host": "cmp1", "state": "up", "disabled": false, "last_seen_up": "2023-03-16 18:35:47"},
+			{"id": 2, "binary": "zun-compute", "host": "cmp2", "state": "down", "disabled": true, "disable_reason": "maintenance"}
+		]}`, []bool{true, true}},
+		{"senlin", "clustering", `{"services": [
+			{"id": "c", "binary": "senlin-engine", "host": "ctl1", "state": "down", "status": "enabled", "updated_at": "2023-03-16T18:35:47.000000"}
+		]}`, []bool{false}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := builtinServiceDrivers[tc.driver]
+
+			var body any
+			require.NoError(t, json.Unmarshal([]byte(tc.body), &body))
+
+			recs, err := d.Records(body)
+			require.NoError(t, err)
+			require.Len(t, recs, len(tc.expected))
+
+			for i, rec := range recs {
+				ok := !d.Enabled(rec) || d.Up(rec)
+				assert.Equal(t, tc.expected[i], ok, rec.Host)
+			}
+		})
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		var body any
+		require.NoError(t, json.Unmarshal([]byte(`{"services": [{"id": 1000000, "binary": "zun-compute", "host": "cmp1", "state": "up", "disabled": true, "last_seen_up": "2023-03-16 18:35:47"}]}`), &body))
+
+		recs, err := builtinServiceDrivers["container"].Records(body)
+		require.NoError(t, err)
+
+		assert := assert.New(t)
+		assert.Equal("1000000", recs[0].ID)
+		assert.True(recs[0].Disabled)
+		assert.Equal(time.Date(2023, 3, 16, 18, 35, 47, 0, time.UTC), recs[0].Heartbeat)
+	})
+}
+
+func TestLoadServiceDrivers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drivers.yaml")
+	err := os.WriteFile(path, []byte(`
+workflow:
+  catalog_type: workflowv2
+  path: services
+  list_path: services
+  fields:
+    host: name
+    binary: type
+    state: state
+  up_values: ["UP"]
+`), 0o644)
+	require.NoError(t, err)
+
+	drivers, err := loadServiceDrivers(path)
+	require.NoError(t, err)
+
+	assert := assert.New(t)
+	assert.Contains(drivers, "orchestration")
+	assert.Equal("workflowv2", drivers["workflow"].CatalogType)
+	assert.Equal("name", drivers["workflow"].Fields.Host)
+	assert.True(drivers["workflow"].Up(ServiceRecord{State: "UP"}))
+}
+
+func TestLoadServiceDriversBuiltinName(t *testing.T) {
+	for _, name := range []string{"compute", "orchestration"} {
+		path := filepath.Join(t.TempDir(), "drivers.yaml")
+		err := os.WriteFile(path, []byte(name+`:
+  catalog_type: workflowv2
+  path: services
+  fields:
+    state: state
+`), 0o644)
+		require.NoError(t, err)
+
+		_, err = loadServiceDrivers(path)
+		assert.ErrorContains(t, err, "name of builtin service", name)
+	}
+}
+
+func TestServiceDriverMicroversionHeader(t *testing.T) {
+	testCases := []struct {
+		header   string
+		expected map[string]string
+	}{
+		{"", map[string]string{"Openstack-Api-Version": "workflowv2 2.1"}},
+		{"OpenStack-API-Version", map[string]string{"Openstack-Api-Version": "workflowv2 2.1"}},
+		{"X-OpenStack-Mistral-API-Version", map[string]string{"X-Openstack-Mistral-Api-Version": "2.1"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.header, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tc.expected {
+					assert.Equal(t, v, r.Header.Get(k))
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"services": [{"name": "eng-1", "state": "up"}]}`)
+			}))
+			defer srv.Close()
+
+			pc := &gophercloud.ProviderClient{TokenID: "token"}
+			pc.EndpointLocator = func(gophercloud.EndpointOpts) (string, error) {
+				return srv.URL + "/", nil
+			}
+
+			d := ServiceDriver{
+				CatalogType:        "workflowv2",
+				Path:               "services",
+				Microversion:       "2.1",
+				MicroversionHeader: tc.header,
+				ListPath:           "services",
+				Fields:             ServiceDriverFields{Host: "name", State: "state"},
+			}
+
+			recs, err := d.List(context.Background(), pc, gophercloud.EndpointOpts{})
+			require.NoError(t, err)
+			require.Len(t, recs, 1)
+			assert.Equal(t, "eng-1", recs[0].Host)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	projectSegment = regexp.MustCompile(`^(AUTH_)?([0-9a-fA-F]{32}|[0-9a-fA-F]{8}-[0-9a-fA-F-]{27})$`)
+	versionSegment = regexp.MustCompile(`^v[0-9]+(\.[0-9]+)?$`)
+)
+
+type EndpointTiming struct {
+	DNS        time.Duration
+	Connect    time.Duration
+	TLS        time.Duration
+	Response   time.Duration
+	Total      time.Duration
+	StatusCode int
+}
+
+// versionRootURL strips the project ID and API version from the catalog URL,
+// e.g. cinder http://host:8776/v3/<project_id> becomes http://host:8776/.
+//
+// Project-scoped URLs respond 401 or 404 without project-scoped request, the version root does not require it.
+func versionRootURL(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	segs := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if n := len(segs); projectSegment.MatchString(segs[n-1]) {
+		segs = segs[:n-1]
+	}
+	if n := len(segs); n > 0 && versionSegment.MatchString(segs[n-1]) {
+		segs = segs[:n-1]
+	}
+
+	u.Path = path.Join(append([]string{"/"}, segs...)...)
+	if u.Path != "/" {
+		u.Path += "/"
+	}
+	u.RawPath = ""
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	return u.String(), nil
+}
+
+// newProbeClient returns HTTP client without keep-alives, so every probe opens a new connection
+// and measures DNS, connect and TLS phases instead of reusing the provider client connections.
+func newProbeClient(tlsCfg *tls.Config) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   tlsCfg,
+			DisableKeepAlives: true,
+		},
+	}
+}
+
+// probeEndpoint issues authenticated GET to the endpoint version document and measures request phases.
+//
+// Only server errors fail the probe, e.g. swift proxy may respond 404 on its root.
+func probeEndpoint(ctx context.Context, cli *http.Client, url, token string) (EndpointTiming, error) {
+	var et EndpointTiming
+	var dnsStart, connectStart, tlsStart, wroteRequest time.Time
+
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:           func(httptrace.DNSDoneInfo) { et.DNS = time.Since(dnsStart) },
+		ConnectStart:      func(string, string) { connectStart = time.Now() },
+		ConnectDone:       func(string, string, error) { et.Connect = time.Since(connectStart) },
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { et.TLS = time.Since(tlsStart) },
+		WroteRequest:      func(httptrace.WroteRequestInfo) { wroteRequest = time.Now() },
+		GotFirstResponseByte: func() {
+			if !wroteRequest.IsZero() {
+				et.Response = time.Since(wroteRequest)
+			}
+		},
+	}
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, url, nil)
+	if err != nil {
+		return et, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Auth-Token", token)
+
+	start := time.Now()
+	resp, err := cli.Do(req)
+	if err != nil {
+		et.Total = time.Since(start)
+		return et, err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.Discard, resp.Body)
+	et.Total = time.Since(start)
+	et.StatusCode = resp.StatusCode
+	if err != nil {
+		return et, err
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return et, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	return et, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionRootURL(t *testing.T) {
+	testCases := []struct {
+		url      string
+		expected string
+	}{
+		{"http://cinder.internal:8776/v3/0c6fa93a0d7b4d9e8f8b0b1c2d3e4f5a", "http://cinder.internal:8776/"},
+		{"https://api.example.com/heat-api/v1/0c6fa93a-0d7b-4d9e-8f8b-0b1c2d3e4f5a", "https://api.example.com/heat-api/"},
+		{"https://swift.example.com:8080/v1/AUTH_0c6fa93a0d7b4d9e8f8b0b1c2d3e4f5a", "https://swift.example.com:8080/"},
+		{"https://api.example.com:8774/v2.1", "https://api.example.com:8774/"},
+		{"https://api.example.com:5000/v3/", "https://api.example.com:5000/"},
+		{"https://api.example.com/image", "https://api.example.com/image/"},
+		{"https://api.example.com:9696", "https://api.example.com:9696/"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			obtained, err := versionRootURL(tc.url)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, obtained)
+		})
+	}
+}
+
+func TestProbeEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusMultipleChoices)
+		case "/broken/":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+
+	rootURL, err := versionRootURL(srv.URL + "/v3/0c6fa93a0d7b4d9e8f8b0b1c2d3e4f5a")
+	require.NoError(t, err)
+
+	et, err := probeEndpoint(ctx, srv.Client(), rootURL, "token")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMultipleChoices, et.StatusCode)
+
+	et, err = probeEndpoint(ctx, srv.Client(), srv.URL+"/v3/0c6fa93a0d7b4d9e8f8b0b1c2d3e4f5a", "token")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, et.StatusCode)
+
+	et, err = probeEndpoint(ctx, srv.Client(), srv.URL+"/broken/", "token")
+	assert.ErrorContains(t, err, "502")
+	assert.Equal(t, http.StatusBadGateway, et.StatusCode)
+}
+
+func TestProbeEndpointNewConnection(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMultipleChoices)
+	}))
+	defer srv.Close()
+
+	cli := newProbeClient(srv.Client().Transport.(*http.Transport).TLSClientConfig)
+
+	// the second probe would reuse the idle connection of a pooled transport
+	for i := 0; i < 2; i++ {
+		et, err := probeEndpoint(context.Background(), cli, srv.URL+"/", "token")
+		require.NoError(t, err)
+		assert.Positive(t, et.Connect, "probe %d", i)
+		assert.Positive(t, et.TLS, "probe %d", i)
+	}
+}
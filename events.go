@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	corev2 "github.com/sensu/core/v2"
+)
+
+// Event grouping of the per-host events.
+const (
+	EventsPerHost       = "host"
+	EventsPerHostBinary = "host-binary"
+	EventsPerAgent      = "agent"
+)
+
+// HostEvent is a result of the service records of one proxy entity.
+type HostEvent struct {
+	Entity    string
+	CheckName string
+	Status    int
+	Output    string
+}
+
+// hostEvents groups service records into proxy entity events.
+//
+// Only service list records are used, as they always contain all hosts, so events resolve when hosts recover.
+func hostEvents(res *Result, per, checkName string) []HostEvent {
+	type group struct {
+		ev    HostEvent
+		lines []string
+	}
+
+	groups := make(map[string]*group)
+	keys := make([]string, 0)
+
+	for _, t := range res.Tables {
+		for _, rec := range t.Records {
+			if rec.Status == nil || rec.Host == "" {
+				continue
+			}
+
+			name := checkName
+			switch per {
+			case EventsPerHostBinary:
+				name += "-" + rec.Binary
+			case EventsPerAgent:
+				name += "-" + rec.Binary
+				if rec.ID != "" {
+					name += "-" + rec.ID
+				}
+			}
+
+			key := rec.Host + "/" + name
+			g, ok := groups[key]
+			if !ok {
+				g = &group{ev: HostEvent{Entity: rec.Host, CheckName: name}}
+				groups[key] = g
+				keys = append(keys, key)
+			}
+
+			g.ev.Status = max(g.ev.Status, rec.State)
+
+			line := fmt.Sprintf("%s@%s: %s", rec.Binary, rec.Host, rec.Verdict)
+			if rec.Reason != "" {
+				line += " " + rec.Reason
+			}
+			g.lines = append(g.lines, line)
+		}
+	}
+
+	sort.Strings(keys)
+
+	ret := make([]HostEvent, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+		g.ev.Output = fmt.Sprintf("%s %s: %d services\n%s\n", stateName(g.ev.Status), res.Service, len(g.lines), strings.Join(g.lines, "\n"))
+		ret = append(ret, g.ev)
+	}
+
+	return ret
+}
+
+// sendHostEvents sends events to the Sensu agent events API (http://) or TCP socket (tcp://).
+//
+// All events are sent even if some fail, so the other hosts still get resolved.
+func sendHostEvents(ctx context.Context, agentAPI string, events []HostEvent, handlers []string) error {
+	u, err := url.Parse(agentAPI)
+	if err != nil {
+		return err
+	}
+
+	send := sendAgentAPIEvent
+	target := u.String()
+	switch u.Scheme {
+	case "http", "https":
+	case "tcp":
+		send, target = sendAgentSocketEvent, u.Host
+	default:
+		return fmt.Errorf("unsupported agent API scheme: %s", u.Scheme)
+	}
+
+	now := time.Now().Unix()
+	errs := make([]error, 0)
+
+	for _, ev := range events {
+		err := send(ctx, target, ev, handlers, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s/%s: %w", ev.Entity, ev.CheckName, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d events failed: %w", len(errs), len(events), errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func sendAgentAPIEvent(ctx context.Context, apiURL string, ev HostEvent, handlers []string, executed int64) error {
+	event := corev2.Event{
+		Check: &corev2.Check{
+			ObjectMeta:      corev2.ObjectMeta{Name: ev.CheckName},
+			Status:          uint32(ev.Status),
+			Output:          ev.Output,
+			Handlers:        handlers,
+			Executed:        executed,
+			ProxyEntityName: ev.Entity,
+		},
+	}
+
+	buf, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("agent API responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// agentSocketResult is the check result format of the agent TCP socket, source is the proxy entity name.
+type agentSocketResult struct {
+	Name     string   `json:"name"`
+	Source   string   `json:"source"`
+	Status   int      `json:"status"`
+	Output   string   `json:"output"`
+	Handlers []string `json:"handlers,omitempty"`
+	Executed int64    `json:"executed"`
+}
+
+func sendAgentSocketEvent(ctx context.Context, addr string, ev HostEvent, handlers []string, executed int64) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	return json.NewEncoder(conn).Encode(agentSocketResult{
+		Name:     ev.CheckName,
+		Source:   ev.Entity,
+		Status:   ev.Status,
+		Output:   ev.Output,
+		Handlers: handlers,
+		Executed: executed,
+	})
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEventsResult() *Result {
+	res := NewResult("network")
+	t := res.AddTable("", "ID", "Binary", "Host")
+
+	for _, s := range []struct {
+		id, binary, host string
+		up               bool
+	}{
+		{"a1", "neutron-dhcp-agent", "net-1", true},
+		{"a2", "neutron-l3-agent", "net-1", false},
+		{"a3", "neutron-dhcp-agent", "net-2", true},
+	} {
+		rec := t.Append(s.id, s.binary, s.host)
+		rec.ID, rec.Binary, rec.Host = s.id, s.binary, s.host
+		evaluateService(rec, true, s.up, time.Time{}, nil)
+	}
+
+	// problem records are not sent
+	pt := res.AddProblemTable("Agent configuration drift", "Host")
+	rec := pt.Append("net-3")
+	rec.Host = "net-3"
+	rec.Fail(sensu.CheckStateWarning, "bridge_mappings differs")
+
+	res.Evaluate()
+	return res
+}
+
+func TestHostEvents(t *testing.T) {
+	res := testEventsResult()
+
+	testCases := []struct {
+		per      string
+		expected []HostEvent
+	}{
+		{EventsPerHost, []HostEvent{
+			{Entity: "net-1", CheckName: "openstack-network", Status: sensu.CheckStateCritical},
+			{Entity: "net-2", CheckName: "openstack-network", Status: sensu.CheckStateOK},
+		}},
+		{EventsPerHostBinary, []HostEvent{
+			{Entity: "net-1", CheckName: "openstack-network-neutron-dhcp-agent", Status: sensu.CheckStateOK},
+			{Entity: "net-1", CheckName: "openstack-network-neutron-l3-agent", Status: sensu.CheckStateCritical},
+			{Entity: "net-2", CheckName: "openstack-network-neutron-dhcp-agent", Status: sensu.CheckStateOK},
+		}},
+		{EventsPerAgent, []HostEvent{
+			{Entity: "net-1", CheckName: "openstack-network-neutron-dhcp-agent-a1", Status: sensu.CheckStateOK},
+			{Entity: "net-1", CheckName: "openstack-network-neutron-l3-agent-a2", Status: sensu.CheckStateCritical},
+			{Entity: "net-2", CheckName: "openstack-network-neutron-dhcp-agent-a3", Status: sensu.CheckStateOK},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.per, func(t *testing.T) {
+			events := hostEvents(res, tc.per, "openstack-network")
+			require.Len(t, events, len(tc.expected))
+
+			for i, ev := range events {
+				assert.Equal(t, tc.expected[i].Entity, ev.Entity)
+				assert.Equal(t, tc.expected[i].CheckName, ev.CheckName)
+				assert.Equal(t, tc.expected[i].Status, ev.Status)
+			}
+		})
+	}
+
+	events := hostEvents(res, EventsPerHost, "openstack-network")
+	assert.Equal(t, "CRITICAL network: 2 services\nneutron-dhcp-agent@net-1: OK\nneutron-l3-agent@net-1: CRITICAL enabled service is down\n", events[0].Output)
+}
+
+func TestSendHostEventsHTTP(t *testing.T) {
+	received := make([]corev2.Event, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev corev2.Event
+		err := json.NewDecoder(r.Body).Decode(&ev)
+		assert.NoError(t, err)
+		received = append(received, ev)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	events := hostEvents(testEventsResult(), EventsPerHost, "openstack-network")
+	err := sendHostEvents(context.Background(), srv.URL+"/events", events, []string{"slack"})
+	require.NoError(t, err)
+
+	require.Len(t, received, 2)
+	assert.Equal(t, "openstack-network", received[0].Check.Name)
+	assert.Equal(t, "net-1", received[0].Check.ProxyEntityName)
+	assert.Equal(t, uint32(sensu.CheckStateCritical), received[0].Check.Status)
+	assert.Equal(t, []string{"slack"}, received[0].Check.Handlers)
+}
+
+func TestSendHostEventsPartialFailure(t *testing.T) {
+	received := make([]string, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev corev2.Event
+		err := json.NewDecoder(r.Body).Decode(&ev)
+		assert.NoError(t, err)
+		received = append(received, ev.Check.ProxyEntityName)
+
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	events := hostEvents(testEventsResult(), EventsPerHost, "openstack-network")
+	require.Len(t, events, 2)
+
+	err := sendHostEvents(context.Background(), srv.URL+"/events", events, nil)
+	assert.ErrorContains(t, err, "1 of 2 events failed")
+	assert.ErrorContains(t, err, events[0].Entity+"/openstack-network")
+	assert.Equal(t, []string{events[0].Entity, events[1].Entity}, received)
+}
+
+func TestSendHostEventsSocket(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan agentSocketResult, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			var r agentSocketResult
+			_ = json.NewDecoder(conn).Decode(&r)
+			conn.Close()
+			received <- r
+		}
+	}()
+
+	events := hostEvents(testEventsResult(), EventsPerHost, "openstack-network")
+	err = sendHostEvents(context.Background(), "tcp://"+ln.Addr().String(), events, nil)
+	require.NoError(t, err)
+
+	r := <-received
+	assert.Equal(t, "openstack-network", r.Name)
+	assert.Equal(t, "net-1", r.Source)
+	assert.Equal(t, sensu.CheckStateCritical, r.Status)
+
+	err = sendHostEvents(context.Background(), "udp://127.0.0.1:3030", events, nil)
+	assert.Error(t, err)
+}
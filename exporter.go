@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu/metric"
+)
+
+// Exporter periodically refreshes service lists and exposes them in the Prometheus text format.
+//
+// Records are fetched and evaluated by the same code as the check, so both agree on the states.
+type Exporter struct {
+	Services []string
+	Interval time.Duration
+	Region   string
+
+	client *OpenStackClient
+
+	mu          sync.RWMutex
+	points      metric.Points
+	lastRefresh time.Time
+	lastErr     error
+}
+
+func NewExporter(services []string, interval time.Duration) *Exporter {
+	return &Exporter{Services: services, Interval: interval}
+}
+
+// Refresh checks all services and replaces exposed metrics.
+func (e *Exporter) Refresh(ctx context.Context) error {
+	start := time.Now()
+
+	if e.client == nil {
+		authCtx, cf := context.WithTimeout(ctx, plugin.timeout)
+		defer cf()
+
+		// keep the token across refreshes
+		oc, err := newOpenStackClient(authCtx, true)
+		if err != nil {
+			e.setError(fmt.Errorf("auth error: %w", err))
+			return err
+		}
+
+		e.client = oc
+		e.Region = oc.eo.Region
+	}
+
+	var errs error
+	points := make(metric.Points, 0)
+
+	for _, service := range e.Services {
+		res := NewResult(service)
+
+		checkCtx, cf := context.WithTimeout(ctx, plugin.timeout)
+		err := e.client.Check(checkCtx, service, res)
+		cf()
+		if err != nil {
+			res.Error(err)
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", service, err))
+		}
+
+		ignoreRecords(res)
+		res.Evaluate()
+		points = append(points, resultMetrics(res, start)...)
+		points = append(points, serviceRecordMetrics(res, start)...)
+	}
+
+	points = append(points,
+		newMetricPoint("openstack_exporter_refresh_duration_seconds", time.Since(start).Seconds(), start.Unix()),
+		newMetricPoint("openstack_exporter_last_refresh_timestamp_seconds", float64(start.Unix()), start.Unix()),
+	)
+
+	for _, p := range points {
+		p.Tags = append(p.Tags, &corev2.MetricTag{Name: "region", Value: e.Region})
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// service errors are exposed by the check state, health reflects the exporter itself
+	e.points = points
+	e.lastRefresh = start
+	e.lastErr = nil
+
+	return errs
+}
+
+func (e *Exporter) setError(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.lastErr = err
+}
+
+// Run refreshes metrics every interval until the context is done.
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := e.Refresh(ctx)
+		if err != nil {
+			log.Printf("Refresh error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeMetrics writes the last refreshed metrics without timestamps.
+func (e *Exporter) ServeMetrics(w http.ResponseWriter, r *http.Request) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	// cached samples have no timestamps, so Prometheus stamps them at scrape and marks them stale when absent
+	err := writeGauges(w, e.points)
+	if err != nil {
+		log.Printf("Metrics write error: %v", err)
+	}
+}
+
+// ServeHealth reports if the exporter is authenticated and the last refresh is not older than two intervals.
+func (e *Exporter) ServeHealth(w http.ResponseWriter, r *http.Request) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	switch {
+	case e.lastErr != nil:
+		http.Error(w, e.lastErr.Error(), http.StatusServiceUnavailable)
+	case time.Since(e.lastRefresh) > 2*e.Interval:
+		http.Error(w, fmt.Sprintf("last refresh at %s", e.lastRefresh.Format(time.RFC3339)), http.StatusServiceUnavailable)
+	default:
+		fmt.Fprintln(w, "ok")
+	}
+}
+
+func (e *Exporter) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", e.ServeMetrics)
+	mux.HandleFunc("/healthz", e.ServeHealth)
+	return mux
+}
+
+func serve(addr string, services []string, interval time.Duration) error {
+	e := NewExporter(services, interval)
+
+	ctx, cf := context.WithCancel(context.Background())
+	defer cf()
+
+	go e.Run(ctx)
+
+	log.Printf("Serving %v metrics on %s", services, addr)
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           e.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return srv.ListenAndServe()
+}
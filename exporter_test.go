@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExporterServeMetrics(t *testing.T) {
+	now := time.Now()
+	res := testMetricsResult(now)
+
+	e := NewExporter([]string{"compute"}, time.Minute)
+	e.points = append(resultMetrics(res, now), serviceRecordMetrics(res, now)...)
+	e.lastRefresh = now
+
+	rr := httptest.NewRecorder()
+	e.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "# TYPE openstack_service_up gauge\n")
+	assert.Contains(t, rr.Body.String(), "# TYPE openstack_service_enabled gauge\n")
+	assert.Contains(t, rr.Body.String(), "# TYPE openstack_check_state gauge\n")
+	assert.Contains(t, rr.Body.String(), "openstack_check_state{service=\"compute\"} 2\n")
+}
+
+func TestExporterServeHealth(t *testing.T) {
+	testCases := []struct {
+		name        string
+		lastRefresh time.Duration
+		lastErr     error
+		expected    int
+	}{
+		{"ok", time.Second, nil, http.StatusOK},
+		{"stale", 3 * time.Minute, nil, http.StatusServiceUnavailable},
+		{"auth-error", time.Second, errors.New("auth error"), http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := NewExporter([]string{"compute"}, time.Minute)
+			e.lastRefresh = time.Now().Add(-tc.lastRefresh)
+			e.lastErr = tc.lastErr
+
+			rr := httptest.NewRecorder()
+			e.Handler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			assert.Equal(t, tc.expected, rr.Code)
+		})
+	}
+}
+
+func TestServiceRecordMetrics(t *testing.T) {
+	now := time.Now()
+	points := serviceRecordMetrics(testMetricsResult(now), now)
+
+	values := make(map[string]float64)
+	for _, p := range points {
+		values[metricPath(p)] = p.Value
+	}
+
+	assert.Len(t, points, 6)
+	assert.Equal(t, 1.0, values["openstack.service_up.compute.nova-compute.nova.cmp-1.none"])
+	assert.Equal(t, 0.0, values["openstack.service_up.compute.nova-compute.nova.cmp-2.none"])
+	assert.Equal(t, 0.0, values["openstack.service_enabled.compute.nova-compute.nova.cmp-3.none"])
+
+	res := NewResult("orchestration")
+	tb := res.AddTable("", "ID")
+	for _, id := range []string{"e1", "e2"} {
+		rec := tb.Append(id)
+		rec.ID, rec.Binary, rec.Host = id, "heat-engine", "ctl-1"
+		evaluateService(rec, true, id == "e1", now, nil)
+	}
+
+	values = make(map[string]float64)
+	for _, p := range serviceRecordMetrics(res, now) {
+		values[metricPath(p)] = p.Value
+	}
+
+	assert.Len(t, values, 4, "engine workers are distinct series")
+	assert.Equal(t, 1.0, values["openstack.service_up.orchestration.heat-engine.none.ctl-1.e1"])
+	assert.Equal(t, 0.0, values["openstack.service_up.orchestration.heat-engine.none.ctl-1.e2"])
+}
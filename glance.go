@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/image/v2/images"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+)
+
+type GlanceStore struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+	ReadOnly    bool   `json:"read-only"`
+}
+
+// GlanceStoreList returns stores of the multi-store deployment (admin only).
+func GlanceStoreList(ctx context.Context, client *gophercloud.ServiceClient) ([]GlanceStore, error) {
+	var s struct {
+		Stores []GlanceStore `json:"stores"`
+	}
+	_, err := client.Get(ctx, client.ServiceURL("info", "stores", "detail"), &s, nil)
+	return s.Stores, err
+}
+
+type GlanceStoreImports struct {
+	Importing []string
+	Failed    []string
+}
+
+// State is critical if the store has stuck imports or failed imports over the threshold.
+func (si *GlanceStoreImports) State(failedThreshold int) (int, string) {
+	switch {
+	case len(si.Importing) > 0:
+		return sensu.CheckStateCritical, "store has stuck imports"
+	case failedThreshold > 0 && len(si.Failed) >= failedThreshold:
+		return sensu.CheckStateCritical, fmt.Sprintf("store has %d failed imports", len(si.Failed))
+	default:
+		return sensu.CheckStateOK, ""
+	}
+}
+
+// imageStoreImports groups stuck and recently failed imports by the target store.
+//
+// Glance tracks import progress in os_glance_importing_to_stores and os_glance_failed_import image properties.
+func imageStoreImports(imgs []images.Image, stuckDeadline, failedSince time.Time) map[string]*GlanceStoreImports {
+	ret := make(map[string]*GlanceStoreImports)
+	get := func(store string) *GlanceStoreImports {
+		si, ok := ret[store]
+		if !ok {
+			si = new(GlanceStoreImports)
+			ret[store] = si
+		}
+		return si
+	}
+
+	for _, img := range imgs {
+		if img.UpdatedAt.Before(stuckDeadline) {
+			for _, store := range imagePropertyList(img, "os_glance_importing_to_stores") {
+				si := get(store)
+				si.Importing = append(si.Importing, img.ID)
+			}
+		}
+
+		if img.UpdatedAt.After(failedSince) {
+			for _, store := range imagePropertyList(img, "os_glance_failed_import") {
+				si := get(store)
+				si.Failed = append(si.Failed, img.ID)
+			}
+		}
+	}
+
+	return ret
+}
+
+func imagePropertyList(img images.Image, key string) []string {
+	v, ok := img.Properties[key]
+	if !ok {
+		return nil
+	}
+
+	ret := make([]string, 0)
+	for _, item := range strings.Split(fmt.Sprint(v), ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			ret = append(ret, item)
+		}
+	}
+
+	return ret
+}
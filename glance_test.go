@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/image/v2/images"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageStoreImports(t *testing.T) {
+	now := time.Now()
+
+	imgs := []images.Image{
+		{ID: "stuck", UpdatedAt: now.Add(-time.Hour), Properties: map[string]any{"os_glance_importing_to_stores": "rbd1, file1"}},
+		{ID: "importing", UpdatedAt: now, Properties: map[string]any{"os_glance_importing_to_stores": "rbd1"}},
+		{ID: "failed", UpdatedAt: now.Add(-time.Hour), Properties: map[string]any{"os_glance_failed_import": "rbd2"}},
+		{ID: "old-failed", UpdatedAt: now.Add(-48 * time.Hour), Properties: map[string]any{"os_glance_failed_import": "rbd2"}},
+	}
+
+	imports := imageStoreImports(imgs, now.Add(-10*time.Minute), now.Add(-24*time.Hour))
+	assert.Equal(t, map[string]*GlanceStoreImports{
+		"rbd1":  {Importing: []string{"stuck"}},
+		"file1": {Importing: []string{"stuck"}},
+		"rbd2":  {Failed: []string{"failed"}},
+	}, imports)
+}
+
+func TestGlanceStoreImportsState(t *testing.T) {
+	testCases := []struct {
+		name   string
+		si     GlanceStoreImports
+		state  int
+		reason string
+	}{
+		{"healthy", GlanceStoreImports{}, sensu.CheckStateOK, ""},
+		{"stuck", GlanceStoreImports{Importing: []string{"i1"}}, sensu.CheckStateCritical, "store has stuck imports"},
+		{"one-failed", GlanceStoreImports{Failed: []string{"i1"}}, sensu.CheckStateOK, ""},
+		{"failed", GlanceStoreImports{Failed: []string{"i1", "i2", "i3"}}, sensu.CheckStateCritical, "store has 3 failed imports"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state, reason := tc.si.State(3)
+			assert.Equal(t, tc.state, state)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
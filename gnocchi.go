@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+)
+
+type GnocchiStatus struct {
+	Storage struct {
+		Summary struct {
+			Metrics  int `json:"metrics"`
+			Measures int `json:"measures"`
+		} `json:"summary"`
+	} `json:"storage"`
+	Metricd struct {
+		Processors []string `json:"processors"`
+	} `json:"metricd"`
+}
+
+func GnocchiStatusGet(ctx context.Context, client *gophercloud.ServiceClient) (*GnocchiStatus, error) {
+	url := client.ServiceURL("v1", "status") + "?details=false"
+
+	var s GnocchiStatus
+	_, err := client.Get(ctx, url, &s, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// evaluateGnocchiStatus appends the backlog and processors records to the table, zero max thresholds are not checked.
+func evaluateGnocchiStatus(t *Table, st *GnocchiStatus, maxMeasures, maxMetrics, minProcessors int) {
+	rec := t.Append("Measures to process", st.Storage.Summary.Measures, maxMeasures)
+	if maxMeasures > 0 && st.Storage.Summary.Measures > maxMeasures {
+		rec.Fail(sensu.CheckStateWarning, "measures backlog over threshold")
+	}
+
+	rec = t.Append("Metrics having measures to process", st.Storage.Summary.Metrics, maxMetrics)
+	if maxMetrics > 0 && st.Storage.Summary.Metrics > maxMetrics {
+		rec.Fail(sensu.CheckStateWarning, "metrics backlog over threshold")
+	}
+
+	rec = t.Append("Metricd processors", len(st.Metricd.Processors), minProcessors)
+	if len(st.Metricd.Processors) < minProcessors {
+		rec.Fail(sensu.CheckStateCritical, "not enough metricd processors")
+	}
+}
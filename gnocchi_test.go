@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGnocchiStatusGet(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "false", r.URL.Query().Get("details"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"storage": {"summary": {"metrics": 12, "measures": 3400}},
+			"metricd": {"processors": ["ctl-1.0", "ctl-1.1", "ctl-2.0"], "statistics": {}}}`)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cli := &gophercloud.ServiceClient{ProviderClient: &gophercloud.ProviderClient{TokenID: "token"}, Endpoint: srv.URL + "/"}
+
+	st, err := GnocchiStatusGet(context.Background(), cli)
+	require.NoError(t, err)
+	assert.Equal(t, 12, st.Storage.Summary.Metrics)
+	assert.Equal(t, 3400, st.Storage.Summary.Measures)
+	assert.Len(t, st.Metricd.Processors, 3)
+}
+
+func TestEvaluateGnocchiStatus(t *testing.T) {
+	status := func(metrics, measures, processors int) *GnocchiStatus {
+		st := &GnocchiStatus{}
+		st.Storage.Summary.Metrics = metrics
+		st.Storage.Summary.Measures = measures
+		st.Metricd.Processors = make([]string, processors)
+		return st
+	}
+
+	testCases := []struct {
+		name                                   string
+		st                                     *GnocchiStatus
+		maxMeasures, maxMetrics, minProcessors int
+		states                                 []int
+	}{
+		{"healthy", status(10, 100, 2), 1000, 100, 1, []int{sensu.CheckStateOK, sensu.CheckStateOK, sensu.CheckStateOK}},
+		{"not-checked", status(10000, 100000, 1), 0, 0, 1, []int{sensu.CheckStateOK, sensu.CheckStateOK, sensu.CheckStateOK}},
+		{"measures", status(10, 1001, 2), 1000, 100, 1, []int{sensu.CheckStateWarning, sensu.CheckStateOK, sensu.CheckStateOK}},
+		{"metrics", status(101, 100, 2), 1000, 100, 1, []int{sensu.CheckStateOK, sensu.CheckStateWarning, sensu.CheckStateOK}},
+		{"at-threshold", status(100, 1000, 1), 1000, 100, 1, []int{sensu.CheckStateOK, sensu.CheckStateOK, sensu.CheckStateOK}},
+		{"no-processors", status(0, 0, 0), 1000, 100, 1, []int{sensu.CheckStateOK, sensu.CheckStateOK, sensu.CheckStateCritical}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := NewResult("metric")
+			tb := res.AddTable("", "Parameter", "Value", "Threshold")
+			evaluateGnocchiStatus(tb, tc.st, tc.maxMeasures, tc.maxMetrics, tc.minProcessors)
+
+			require.Len(t, tb.Records, 3)
+			for i, state := range tc.states {
+				assert.Equal(t, state, tb.Records[i].State, tb.Records[i].Fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/containerinfra/v1/clusters"
+	"github.com/gophercloud/gophercloud/v2/pagination"
+)
+
+type MagnumService struct {
+	Binary         string  `json:"binary"`
+	DisabledReason string  `json:"disabled_reason"`
+	Disabled       bool    `json:"disabled"`
+	Host           string  `json:"host"`
+	ID             int     `json:"id"`
+	ReportCount    int     `json:"report_count"`
+	State          string  `json:"state"`
+	CreatedAt      AnyTime `json:"created_at"`
+	UpdatedAt      AnyTime `json:"updated_at"`
+}
+
+type MagnumServicePage struct {
+	pagination.SinglePageBase
+}
+
+func (page MagnumServicePage) IsEmpty() (bool, error) {
+	if page.StatusCode == 204 {
+		return true, nil
+	}
+
+	services, err := ExtractMagnumServices(page)
+	return len(services) == 0, err
+}
+
+func ExtractMagnumServices(r pagination.Page) ([]MagnumService, error) {
+	var s struct {
+		Services []MagnumService `json:"mservices"`
+	}
+	err := (r.(MagnumServicePage)).ExtractInto(&s)
+	return s.Services, err
+}
+
+func MagnumServiceList(client *gophercloud.ServiceClient) pagination.Pager {
+	url := client.ServiceURL("mservices")
+
+	return pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
+		return MagnumServicePage{pagination.SinglePageBase(r)}
+	})
+}
+
+// clusterProblem returns "failed" or "stuck" for clusters in *_FAILED or long running *_IN_PROGRESS states.
+func clusterProblem(c clusters.Cluster, deadline time.Time) string {
+	switch {
+	case strings.HasSuffix(c.Status, "_FAILED"):
+		return "failed"
+
+	case strings.HasSuffix(c.Status, "_IN_PROGRESS"):
+		changed := c.UpdatedAt
+		if changed.IsZero() {
+			changed = c.CreatedAt
+		}
+
+		if changed.Before(deadline) {
+			return "stuck"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/containerinfra/v1/clusters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMagnumServiceList(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /mservices", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"mservices": [{"id": 1, "binary": "magnum-conductor", "host": "ctl-1", "state": "down",
+			"disabled": true, "disabled_reason": "maintenance", "report_count": 42,
+			"created_at": "2024-01-01T00:00:00+00:00", "updated_at": "2024-05-01T10:00:00+00:00"}]}`)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cli := &gophercloud.ServiceClient{ProviderClient: &gophercloud.ProviderClient{TokenID: "token"}, Endpoint: srv.URL + "/"}
+
+	pages, err := MagnumServiceList(cli).AllPages(context.Background())
+	require.NoError(t, err)
+
+	srvs, err := ExtractMagnumServices(pages)
+	require.NoError(t, err)
+	require.Len(t, srvs, 1)
+	assert.Equal(t, "magnum-conductor", srvs[0].Binary)
+	assert.True(t, srvs[0].Disabled)
+	assert.Equal(t, "maintenance", srvs[0].DisabledReason)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), srvs[0].UpdatedAt.As().UTC())
+}
+
+func TestClusterProblem(t *testing.T) {
+	now := time.Now()
+	deadline := now.Add(-10 * time.Minute)
+	old := now.Add(-time.Hour)
+
+	testCases := []struct {
+		name     string
+		cluster  clusters.Cluster
+		expected string
+	}{
+		{"complete", clusters.Cluster{Status: "CREATE_COMPLETE", UpdatedAt: old}, ""},
+		{"create-failed", clusters.Cluster{Status: "CREATE_FAILED", UpdatedAt: now}, "failed"},
+		{"update-failed", clusters.Cluster{Status: "UPDATE_FAILED", UpdatedAt: old}, "failed"},
+		{"in-progress", clusters.Cluster{Status: "UPDATE_IN_PROGRESS", UpdatedAt: now}, ""},
+		{"stuck", clusters.Cluster{Status: "UPDATE_IN_PROGRESS", UpdatedAt: old}, "stuck"},
+		{"never-updated", clusters.Cluster{Status: "CREATE_IN_PROGRESS", CreatedAt: old}, "stuck"},
+		{"just-created", clusters.Cluster{Status: "CREATE_IN_PROGRESS", CreatedAt: now}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, clusterProblem(tc.cluster, deadline))
+		})
+	}
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"maps"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -14,12 +20,24 @@ import (
 	"github.com/gophercloud/gophercloud/v2/openstack"
 	"github.com/gophercloud/gophercloud/v2/openstack/baremetal/v1/conductors"
 	volsrv "github.com/gophercloud/gophercloud/v2/openstack/blockstorage/v3/services"
+	"github.com/gophercloud/gophercloud/v2/openstack/compute/v2/servers"
 	cptsrv "github.com/gophercloud/gophercloud/v2/openstack/compute/v2/services"
 	"github.com/gophercloud/gophercloud/v2/openstack/config"
 	clouds "github.com/gophercloud/gophercloud/v2/openstack/config/clouds"
+	"github.com/gophercloud/gophercloud/v2/openstack/containerinfra/v1/clusters"
+	"github.com/gophercloud/gophercloud/v2/openstack/dns/v2/zones"
+	"github.com/gophercloud/gophercloud/v2/openstack/image/v2/images"
+	"github.com/gophercloud/gophercloud/v2/openstack/image/v2/tasks"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/amphorae"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	netagents "github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/agents"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/external"
+	ipavail "github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/networkipavailabilities"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/subnets"
 	sharesrv "github.com/gophercloud/gophercloud/v2/openstack/sharedfilesystems/v2/services"
 	oscli "github.com/gophercloud/utils/v2/client"
-	"github.com/jedib0t/go-pretty/v6/table"
 	corev2 "github.com/sensu/core/v2"
 	"github.com/sensu/sensu-plugin-sdk/sensu"
 )
@@ -31,7 +49,73 @@ type Config struct {
 	CloudsFile             string
 	Service                string
 	CriticalDisabledReason []string
+	IgnoreHosts            []string
+	ExcludeBinaries        []string
+	DHCPAgentsPerNetwork   int
+	OVNMinGateways         int
+	OVNCheckMetadata       bool
+	ConfigDrift            bool
+	DriftBaseline          map[string]string
+	IgnoreDeviceOwner      []string
+	GracePeriod            string
+	TimeWindow             string
+	ImageFailedImports     int
+	DriversFile            string
+	MetricMaxMeasures      int
+	MetricMaxMetrics       int
+	MetricMinProcessors    int
+	SwiftContainer         string
+	LatencyWarning         string
+	LatencyCritical        string
+	CatalogRegions         []string
+	CatalogInterfaces      []string
+	InterfaceLatencyWarn   map[string]string
+	InterfaceLatencyCrit   map[string]string
+	CertWarningDays        int
+	CertCriticalDays       int
+	Timeout                string
+	CanaryTag              string
+	CanaryImage            string
+	CanaryFlavor           string
+	CanaryNetwork          string
+	CanaryCIDR             string
+	CanaryVolumeSize       int
+	CanaryWarning          string
+	CanaryCritical         string
+	Output                 string
+	Show                   string
+	Listen                 string
+	TextfileDir            string
+	Host                   string
+	HostFromEntity         bool
+	HostEntityLabel        string
+	EventsPer              string
+	AgentAPI               string
+	EventCheckName         string
+	EventHandlers          []string
+	RefreshInterval        string
+	Services               []string
+	IPNetworks             []string
+	IPNetworkTags          []string
+	IPExternal             bool
+	IPWarning              float64
+	IPCritical             float64
+	PrintConfig            bool
 	Debug                  bool
+
+	gracePeriod time.Duration
+	timeWindow  time.Duration
+	drivers     map[string]ServiceDriver
+
+	latencyWarning  time.Duration
+	latencyCritical time.Duration
+	ifaceLatency    map[string][2]time.Duration
+	timeout         time.Duration
+	canaryWarning   time.Duration
+	canaryCritical  time.Duration
+	refreshInterval time.Duration
+	overrides       []Override
+	serve           bool
 }
 
 var (
@@ -66,8 +150,7 @@ var (
 			Argument:  "service",
 			Shorthand: "s",
 			Default:   "compute",
-			Allow:     []string{"compute", "volume", "sharev2", "network", "orchestration", "container", "clustering", "baremetal"},
-			Usage:     "Service to check",
+			Usage:     "Service to check: compute, volume, sharev2, network, network-ports, network-ip-availability, baremetal, load-balancer, dns, container-infra, instance-ha, metric, object-store, image, catalog, endpoints, certificates, canary or a service driver (orchestration, container, clustering, custom)",
 			Value:     &plugin.Service,
 		},
 		&sensu.SlicePluginConfigOption[string]{
@@ -77,6 +160,348 @@ var (
 			Usage:     "Critical error from disabled reason (regexp)",
 			Value:     &plugin.CriticalDisabledReason,
 		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     "ignore_hosts",
+			Argument: "ignore-hosts",
+			Usage:    "Ignore records of the hosts (regexp)",
+			Value:    &plugin.IgnoreHosts,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     "exclude_binaries",
+			Argument: "exclude-binaries",
+			Usage:    "Ignore records of the service binaries",
+			Value:    &plugin.ExcludeBinaries,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "dhcp_agents_per_network",
+			Argument: "dhcp-agents-per-network",
+			Default:  0,
+			Usage:    "Minimal number of alive DHCP agents hosting each network with DHCP-enabled subnets (0 - do not check)",
+			Value:    &plugin.DHCPAgentsPerNetwork,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "ovn_min_gateways",
+			Argument: "ovn-min-gateways",
+			Default:  0,
+			Usage:    "Minimal number of alive OVN gateway chassis (0 - do not check)",
+			Value:    &plugin.OVNMinGateways,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     "ovn_check_metadata",
+			Argument: "ovn-check-metadata",
+			Default:  false,
+			Usage:    "Require alive OVN metadata agent on each OVN controller chassis",
+			Value:    &plugin.OVNCheckMetadata,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     "config_drift",
+			Argument: "config-drift",
+			Default:  false,
+			Usage:    "Warn on agents which bridge mappings or tunnel types differ from the majority of the same agent binary",
+			Value:    &plugin.ConfigDrift,
+		},
+		&sensu.MapPluginConfigOption[string]{
+			Path:     "drift_baseline",
+			Argument: "drift-baseline",
+			Usage:    "Expected agent configuration value instead of the majority by agent binary and key, e.g. neutron-openvswitch-agent/bridge_mappings=\"physnet1:br-ex physnet2:br-vlan\"",
+			Value:    &plugin.DriftBaseline,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     "ignore_device_owner",
+			Argument: "ignore-device-owner",
+			Default:  []string{"network:floatingip", "network:router_interface_distributed", "network:distributed"},
+			Usage:    "Device owners of ports which are not bound by design",
+			Value:    &plugin.IgnoreDeviceOwner,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "grace_period",
+			Argument: "grace-period",
+			Default:  "10m",
+			Usage:    "Time to wait before reporting resource stuck in transitional state",
+			Value:    &plugin.GracePeriod,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "time_window",
+			Argument: "time-window",
+			Default:  "24h",
+			Usage:    "How far back to look for recent failures",
+			Value:    &plugin.TimeWindow,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "image_failed_imports",
+			Argument: "image-failed-imports",
+			Default:  3,
+			Usage:    "Critical if image store has that many failed imports within the time window",
+			Value:    &plugin.ImageFailedImports,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     "ip_networks",
+			Argument: "ip-network",
+			Usage:    "Network name to check IP availability",
+			Value:    &plugin.IPNetworks,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     "ip_network_tags",
+			Argument: "ip-network-tag",
+			Usage:    "Check IP availability of networks having any of tags",
+			Value:    &plugin.IPNetworkTags,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     "ip_external",
+			Argument: "ip-external",
+			Default:  true,
+			Usage:    "Check IP availability of external (router:external) networks",
+			Value:    &plugin.IPExternal,
+		},
+		&sensu.PluginConfigOption[float64]{
+			Path:     "ip_warning",
+			Argument: "ip-warning",
+			Default:  80,
+			Usage:    "Warning threshold of used IPs per subnet (percent)",
+			Value:    &plugin.IPWarning,
+		},
+		&sensu.PluginConfigOption[float64]{
+			Path:     "ip_critical",
+			Argument: "ip-critical",
+			Default:  90,
+			Usage:    "Critical threshold of used IPs per subnet (percent)",
+			Value:    &plugin.IPCritical,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "drivers-file",
+			Default:  "",
+			Usage:    "YAML file with additional service driver definitions",
+			Value:    &plugin.DriversFile,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "metric_max_measures",
+			Argument: "metric-max-measures",
+			Default:  0,
+			Usage:    "Warning if Gnocchi measures to process exceed (0 - do not check)",
+			Value:    &plugin.MetricMaxMeasures,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "metric_max_metrics",
+			Argument: "metric-max-metrics",
+			Default:  0,
+			Usage:    "Warning if Gnocchi metrics having measures to process exceed (0 - do not check)",
+			Value:    &plugin.MetricMaxMetrics,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "metric_min_processors",
+			Argument: "metric-min-processors",
+			Default:  1,
+			Usage:    "Minimal number of alive Gnocchi metricd processors",
+			Value:    &plugin.MetricMinProcessors,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "swift-container",
+			Default:  "",
+			Usage:    "Container for object PUT/GET/DELETE round trip probe (empty - do not probe)",
+			Value:    &plugin.SwiftContainer,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "latency_warning",
+			Argument: "latency-warning",
+			Default:  "1s",
+			Usage:    "Warning threshold of API response time",
+			Value:    &plugin.LatencyWarning,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "latency_critical",
+			Argument: "latency-critical",
+			Default:  "5s",
+			Usage:    "Critical threshold of API response time",
+			Value:    &plugin.LatencyCritical,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     "catalog_regions",
+			Argument: "catalog-region",
+			Usage:    "Region expected to have endpoints of every service (default: all regions found in the catalog)",
+			Value:    &plugin.CatalogRegions,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Path:     "catalog_interfaces",
+			Argument: "catalog-interface",
+			Default:  []string{"public", "internal", "admin"},
+			Usage:    "Interface expected for every service",
+			Value:    &plugin.CatalogInterfaces,
+		},
+		&sensu.MapPluginConfigOption[string]{
+			Path:     "interface_latency_warning",
+			Argument: "interface-latency-warning",
+			Usage:    "Per interface warning threshold of API response time, e.g. internal=500ms, identity key applies to token issue (default: --latency-warning)",
+			Value:    &plugin.InterfaceLatencyWarn,
+		},
+		&sensu.MapPluginConfigOption[string]{
+			Path:     "interface_latency_critical",
+			Argument: "interface-latency-critical",
+			Usage:    "Per interface critical threshold of API response time, e.g. public=3s, identity key applies to token issue (default: --latency-critical)",
+			Value:    &plugin.InterfaceLatencyCrit,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "cert_warning_days",
+			Argument: "cert-warning-days",
+			Default:  30,
+			Usage:    "Warning if endpoint certificate expires within days",
+			Value:    &plugin.CertWarningDays,
+		},
+		&sensu.PluginConfigOption[int]{
+			Path:     "cert_critical_days",
+			Argument: "cert-critical-days",
+			Default:  7,
+			Usage:    "Critical if endpoint certificate expires within days",
+			Value:    &plugin.CertCriticalDays,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "timeout",
+			Argument: "timeout",
+			Default:  "1m",
+			Usage:    "Check execution timeout (canary needs several minutes, greater than --canary-critical)",
+			Value:    &plugin.Timeout,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "canary-tag",
+			Default:  "sensu-canary",
+			Usage:    "Tag and name of the canary resources, all resources having the tag are deleted",
+			Value:    &plugin.CanaryTag,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "canary-image",
+			Default:  "",
+			Usage:    "Image ID to boot canary server",
+			Value:    &plugin.CanaryImage,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "canary-flavor",
+			Default:  "",
+			Usage:    "Flavor ID of canary server",
+			Value:    &plugin.CanaryFlavor,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "canary-network",
+			Default:  "",
+			Usage:    "Existing network ID for canary port (empty - create network)",
+			Value:    &plugin.CanaryNetwork,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "canary-cidr",
+			Default:  "192.168.254.0/24",
+			Usage:    "Subnet CIDR of created canary network",
+			Value:    &plugin.CanaryCIDR,
+		},
+		&sensu.PluginConfigOption[int]{
+			Argument: "canary-volume-size",
+			Default:  1,
+			Usage:    "Size of canary volume in GB (0 - do not test volume attachment)",
+			Value:    &plugin.CanaryVolumeSize,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "canary_warning",
+			Argument: "canary-warning",
+			Default:  "1m",
+			Usage:    "Warning threshold of canary step duration",
+			Value:    &plugin.CanaryWarning,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "canary_critical",
+			Argument: "canary-critical",
+			Default:  "3m",
+			Usage:    "Critical threshold of canary step duration",
+			Value:    &plugin.CanaryCritical,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:      "output",
+			Argument:  "output",
+			Shorthand: "o",
+			Default:   "text",
+			Allow:     append([]string{"text", "json"}, metricFormats...),
+			Usage:     "Output format: text tables, JSON document or Sensu metric format (service counts and heartbeat ages)",
+			Value:     &plugin.Output,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "host",
+			Argument: "host",
+			Default:  "",
+			Usage:    "Check only services of the host across --services",
+			Value:    &plugin.Host,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     "host_from_entity",
+			Argument: "host-from-entity",
+			Default:  false,
+			Usage:    "Check only services of the host named by the event entity (proxy check with stdin)",
+			Value:    &plugin.HostFromEntity,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "host_entity_label",
+			Argument: "host-entity-label",
+			Default:  "",
+			Usage:    "Entity label with the host name, used instead of the entity name (implies --host-from-entity)",
+			Value:    &plugin.HostEntityLabel,
+		},
+		&sensu.PluginConfigOption[string]{
+			Path:     "show",
+			Argument: "show",
+			Default:  ShowAll,
+			Allow:    []string{ShowAll, ShowFailing, ShowNone},
+			Usage:    "Table records printed after the summary line: all, failing or none",
+			Value:    &plugin.Show,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "textfile-dir",
+			Default:  "",
+			Usage:    "Directory of node_exporter textfile collector to write service state metrics",
+			Value:    &plugin.TextfileDir,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "events-per",
+			Default:  "",
+			Allow:    []string{"", EventsPerHost, EventsPerHostBinary, EventsPerAgent},
+			Usage:    "Also send proxy entity event per host, host-binary or agent to the Sensu agent",
+			Value:    &plugin.EventsPer,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "agent-api",
+			Default:  "http://127.0.0.1:3031/events",
+			Usage:    "Sensu agent events API URL or TCP socket address (tcp://127.0.0.1:3030)",
+			Value:    &plugin.AgentAPI,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "event-check-name",
+			Default:  "",
+			Usage:    "Check name of the proxy entity events, default is the check name or openstack-<service>",
+			Value:    &plugin.EventCheckName,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Argument: "event-handlers",
+			Usage:    "Handlers of the proxy entity events",
+			Value:    &plugin.EventHandlers,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "listen",
+			Default:  ":9180",
+			Usage:    "Address of the Prometheus metrics in the serve mode",
+			Value:    &plugin.Listen,
+		},
+		&sensu.PluginConfigOption[string]{
+			Argument: "refresh-interval",
+			Default:  "1m",
+			Usage:    "Interval of service lists refresh in the serve mode",
+			Value:    &plugin.RefreshInterval,
+		},
+		&sensu.SlicePluginConfigOption[string]{
+			Argument: "services",
+			Usage:    "Services to refresh in the serve mode, default is --service",
+			Value:    &plugin.Services,
+		},
+		&sensu.PluginConfigOption[bool]{
+			Path:     "print_config",
+			Argument: "print-config",
+			Default:  false,
+			Usage:    "Print effective configuration after overrides from labels and annotations",
+			Value:    &plugin.PrintConfig,
+		},
 		&sensu.PluginConfigOption[bool]{
 			Argument:  "debug",
 			Shorthand: "d",
@@ -86,6 +511,28 @@ var (
 	}
 )
 
+var builtinServices = []string{"compute", "volume", "sharev2", "network", "network-ports", "network-ip-availability", "baremetal", "load-balancer", "dns", "container-infra", "instance-ha", "metric", "object-store", "image", "catalog", "endpoints", "certificates", "canary"}
+
+func latencyState(latency time.Duration) int {
+	return interfaceLatencyState("", latency)
+}
+
+func interfaceLatencyState(iface string, latency time.Duration) int {
+	lt, ok := plugin.ifaceLatency[iface]
+	if !ok {
+		lt = [2]time.Duration{plugin.latencyWarning, plugin.latencyCritical}
+	}
+
+	switch {
+	case latency >= lt[1]:
+		return sensu.CheckStateCritical
+	case latency >= lt[0]:
+		return sensu.CheckStateWarning
+	default:
+		return sensu.CheckStateOK
+	}
+}
+
 func reasonMatch(reason string, regexps []string) bool {
 	for _, pattern := range regexps {
 		match, err := regexp.Match(pattern, []byte(reason))
@@ -103,13 +550,19 @@ func reasonMatch(reason string, regexps []string) bool {
 }
 
 func main() {
+	// the check workflow gets no positional arguments, so the serve mode is cut before flags parsing
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		plugin.serve = true
+		os.Args = slices.Delete(os.Args, 1, 2)
+	}
+
 	useStdin := false
 	fi, err := os.Stdin.Stat()
 	if err != nil {
 		fmt.Printf("Error check stdin: %v\n", err)
 	}
 	// Check the Mode bitmask for Named Pipe to indicate stdin is connected
-	if fi.Mode()&os.ModeNamedPipe != 0 {
+	if fi.Mode()&os.ModeNamedPipe != 0 && !plugin.serve {
 		useStdin = true
 	}
 
@@ -118,94 +571,1018 @@ func main() {
 }
 
 func checkArgs(event *corev2.Event) (int, error) {
-	for _, pattern := range plugin.CriticalDisabledReason {
+	var err error
+	plugin.overrides, err = applyOverrides(plugin.Keyspace, options, overridablePaths, event, os.Stderr)
+	if err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("Failed to apply override: %w", err)
+	}
+
+	for _, pattern := range slices.Concat(plugin.CriticalDisabledReason, plugin.IgnoreHosts) {
 		_, err := regexp.Compile(pattern)
 		if err != nil {
 			return sensu.CheckStateCritical, fmt.Errorf("Failed to compile regexp: %s: %w", pattern, err)
 		}
-	}
+	}
+
+	plugin.gracePeriod, err = time.ParseDuration(plugin.GracePeriod)
+	if err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("Failed to parse grace period: %w", err)
+	}
+
+	plugin.timeWindow, err = time.ParseDuration(plugin.TimeWindow)
+	if err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("Failed to parse time window: %w", err)
+	}
+
+	plugin.latencyWarning, err = time.ParseDuration(plugin.LatencyWarning)
+	if err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("Failed to parse latency warning: %w", err)
+	}
+
+	plugin.latencyCritical, err = time.ParseDuration(plugin.LatencyCritical)
+	if err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("Failed to parse latency critical: %w", err)
+	}
+
+	plugin.ifaceLatency = make(map[string][2]time.Duration)
+	for i, thresholds := range []map[string]string{plugin.InterfaceLatencyWarn, plugin.InterfaceLatencyCrit} {
+		for iface, value := range thresholds {
+			lt, ok := plugin.ifaceLatency[iface]
+			if !ok {
+				lt = [2]time.Duration{plugin.latencyWarning, plugin.latencyCritical}
+			}
+
+			lt[i], err = time.ParseDuration(value)
+			if err != nil {
+				return sensu.CheckStateCritical, fmt.Errorf("Failed to parse %s interface latency: %w", iface, err)
+			}
+
+			plugin.ifaceLatency[iface] = lt
+		}
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"timeout", plugin.Timeout, &plugin.timeout},
+		{"canary warning", plugin.CanaryWarning, &plugin.canaryWarning},
+		{"canary critical", plugin.CanaryCritical, &plugin.canaryCritical},
+		{"refresh interval", plugin.RefreshInterval, &plugin.refreshInterval},
+	} {
+		*d.dst, err = time.ParseDuration(d.value)
+		if err != nil {
+			return sensu.CheckStateCritical, fmt.Errorf("Failed to parse %s: %w", d.name, err)
+		}
+	}
+
+	if plugin.serve && plugin.refreshInterval <= 0 {
+		return sensu.CheckStateCritical, fmt.Errorf("refresh interval must be positive")
+	}
+
+	if plugin.Service == "canary" || slices.Contains(plugin.Services, "canary") {
+		if plugin.CanaryImage == "" || plugin.CanaryFlavor == "" {
+			return sensu.CheckStateCritical, fmt.Errorf("canary requires --canary-image and --canary-flavor")
+		}
+
+		// otherwise slow steps are reported as the deadline errors
+		if plugin.timeout <= plugin.canaryCritical {
+			return sensu.CheckStateCritical, fmt.Errorf("timeout %s must be greater than canary critical %s", plugin.timeout, plugin.canaryCritical)
+		}
+	}
+
+	err = validateDriftBaseline(plugin.DriftBaseline)
+	if err != nil {
+		return sensu.CheckStateCritical, err
+	}
+
+	plugin.drivers, err = loadServiceDrivers(plugin.DriversFile)
+	if err != nil {
+		return sensu.CheckStateCritical, fmt.Errorf("Failed to load service drivers: %w", err)
+	}
+
+	if len(plugin.Services) == 0 {
+		plugin.Services = []string{plugin.Service}
+	}
+
+	for _, service := range append([]string{plugin.Service}, plugin.Services...) {
+		if !slices.Contains(builtinServices, service) && plugin.drivers[service].CatalogType == "" {
+			return sensu.CheckStateCritical, fmt.Errorf("unsupported service: %s", service)
+		}
+	}
+
+	// every refresh would boot a server
+	if plugin.serve && slices.Contains(plugin.Services, "canary") {
+		return sensu.CheckStateCritical, fmt.Errorf("canary service cannot be refreshed in the serve mode")
+	}
+
+	// all proxy checks would replace the same file without host label
+	if plugin.TextfileDir != "" && (plugin.Host != "" || plugin.HostFromEntity || plugin.HostEntityLabel != "") {
+		return sensu.CheckStateCritical, fmt.Errorf("--textfile-dir cannot be used with the host filter")
+	}
+
+	if plugin.IPWarning > plugin.IPCritical {
+		return sensu.CheckStateCritical, fmt.Errorf("IP warning threshold %.1f greater than critical %.1f", plugin.IPWarning, plugin.IPCritical)
+	}
+
+	if plugin.CertWarningDays < plugin.CertCriticalDays {
+		return sensu.CheckStateCritical, fmt.Errorf("certificate warning days %d less than critical %d", plugin.CertWarningDays, plugin.CertCriticalDays)
+	}
+
+	if plugin.latencyWarning > plugin.latencyCritical {
+		return sensu.CheckStateCritical, fmt.Errorf("latency warning %s greater than critical %s", plugin.latencyWarning, plugin.latencyCritical)
+	}
+
+	if plugin.canaryWarning > plugin.canaryCritical {
+		return sensu.CheckStateCritical, fmt.Errorf("canary warning %s greater than critical %s", plugin.canaryWarning, plugin.canaryCritical)
+	}
+
+	return sensu.CheckStateOK, nil
+}
+
+func executeCheck(event *corev2.Event) (int, error) {
+	if plugin.serve {
+		return sensu.CheckStateUnknown, serve(plugin.Listen, plugin.Services, plugin.refreshInterval)
+	}
+
+	host, err := checkHost(event)
+	if err != nil {
+		return sensu.CheckStateUnknown, err
+	}
+
+	res := NewResult(plugin.Service)
+	if host != "" {
+		res = NewResult(strings.Join(plugin.Services, ","))
+		res.Host = host
+	}
+
+	err = runCheck(res, host)
+	if err != nil {
+		res.Error(err)
+	}
+
+	ignoreRecords(res)
+
+	res.Overrides = plugin.overrides
+	if plugin.PrintConfig {
+		res.Config = effectiveConfig(options)
+	}
+
+	ret := res.Evaluate()
+
+	if plugin.TextfileDir != "" {
+		terr := writeTextfile(plugin.TextfileDir, res, time.Now())
+		if terr != nil {
+			terr = fmt.Errorf("Textfile write error: %w", terr)
+			res.Error(terr)
+			err = errors.Join(err, terr)
+			ret = res.Evaluate()
+		}
+	}
+
+	if plugin.EventsPer != "" {
+		checkName := plugin.EventCheckName
+		if checkName == "" && event != nil && event.Check != nil {
+			checkName = event.Check.Name
+		}
+		if checkName == "" {
+			checkName = "openstack-" + plugin.Service
+		}
+
+		ctx, cf := context.WithTimeout(context.Background(), plugin.timeout)
+		defer cf()
+
+		serr := sendHostEvents(ctx, plugin.AgentAPI, hostEvents(res, plugin.EventsPer, checkName), plugin.EventHandlers)
+		if serr != nil {
+			serr = fmt.Errorf("Event send error: %w", serr)
+			res.Error(serr)
+			err = errors.Join(err, serr)
+			ret = res.Evaluate()
+		}
+	}
+
+	switch plugin.Output {
+	case "text":
+		res.RenderText(os.Stdout, plugin.Show)
+
+	case "json":
+		// errors are the part of the document
+		return ret, res.RenderJSON(os.Stdout)
+
+	default:
+		// tables are not printed, they break metric parsers
+		werr := writeMetrics(os.Stdout, plugin.Output, res, resultMetrics(res, time.Now()))
+		if werr != nil {
+			return sensu.CheckStateUnknown, werr
+		}
+	}
+
+	return ret, err
+}
+
+func runCheck(res *Result, host string) error {
+	ctx, cf := context.WithTimeout(context.Background(), plugin.timeout)
+	defer cf()
+
+	// check never need to reauth
+	oc, err := newOpenStackClient(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	if host == "" {
+		return oc.Check(ctx, plugin.Service, res)
+	}
+
+	found := false
+	for _, service := range plugin.Services {
+		sres := NewResult(service)
+
+		err := oc.Check(ctx, service, sres)
+		if err != nil {
+			sres.Error(err)
+		}
+
+		hres := sres.FilterHost(host)
+		found = found || len(hres.Tables) > 0
+		res.Merge(hres)
+	}
+
+	if !found {
+		res.Fail(sensu.CheckStateUnknown, "No records of host %s found in services: %s", host, strings.Join(plugin.Services, ", "))
+	}
+
+	return nil
+}
+
+// ignoreRecords drops records of the ignored hosts and excluded binaries.
+func ignoreRecords(res *Result) {
+	dropped := res.DropRecords(func(rec *Record) bool {
+		return (rec.Host != "" && reasonMatch(rec.Host, plugin.IgnoreHosts)) ||
+			(rec.Binary != "" && slices.Contains(plugin.ExcludeBinaries, rec.Binary))
+	})
+
+	if dropped > 0 {
+		res.Printf("Ignored %d records of ignored hosts and excluded binaries", dropped)
+	}
+}
+
+// checkHost returns the host filter from the options or the event entity.
+func checkHost(event *corev2.Event) (string, error) {
+	if !plugin.HostFromEntity && plugin.HostEntityLabel == "" {
+		return plugin.Host, nil
+	}
+
+	if event == nil || event.Entity == nil {
+		return "", fmt.Errorf("host from entity requires the event on stdin")
+	}
+
+	if plugin.HostEntityLabel == "" {
+		return event.Entity.Name, nil
+	}
+
+	host, ok := event.Entity.Labels[plugin.HostEntityLabel]
+	if !ok || host == "" {
+		return "", fmt.Errorf("entity %s has no %s label", event.Entity.Name, plugin.HostEntityLabel)
+	}
+
+	return host, nil
+}
+
+// OpenStackClient holds authenticated provider client and parameters shared by the service checks.
+type OpenStackClient struct {
+	pc          *gophercloud.ProviderClient
+	eo          gophercloud.EndpointOpts
+	tlsCfg      *tls.Config
+	authLatency time.Duration
+}
+
+func newOpenStackClient(ctx context.Context, allowReauth bool) (*OpenStackClient, error) {
+	var httpCli *http.Client
+	if plugin.Debug {
+		httpCli = &http.Client{
+			Transport: &oscli.RoundTripper{
+				Rt:     &http.Transport{},
+				Logger: &oscli.DefaultLogger{},
+			},
+		}
+	} else {
+		httpCli = &http.Client{Transport: &http.Transport{}}
+	}
+
+	pOpts := []clouds.ParseOption{clouds.WithCloudName(plugin.Cloud)}
+	if plugin.CloudsFile != "" {
+		pOpts = append(pOpts, clouds.WithLocations(plugin.CloudsFile))
+	}
+
+	ao, eo, tlsCfg, err := clouds.Parse(pOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ao.AllowReauth = allowReauth
+
+	authStart := time.Now()
+	pc, err := config.NewProviderClient(ctx, ao, config.WithHTTPClient(*httpCli), config.WithTLSConfig(tlsCfg))
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenStackClient{pc: pc, eo: eo, tlsCfg: tlsCfg, authLatency: time.Since(authStart)}, nil
+}
+
+// Check fetches and evaluates the service records.
+func (oc *OpenStackClient) Check(ctx context.Context, service string, res *Result) error {
+	pc, eo, tlsCfg, authLatency := oc.pc, oc.eo, oc.tlsCfg, oc.authLatency
+
+	switch service {
+	case "compute":
+		return checkCompute(ctx, pc, eo, res)
+
+	case "volume":
+		return checkVolume(ctx, pc, eo, res)
+
+	case "sharev2":
+		return checkShare(ctx, pc, eo, res)
+
+	case "network":
+		return checkNetwork(ctx, pc, eo, res)
+
+	case "baremetal":
+		return checkBaremetal(ctx, pc, eo, res)
+
+	case "network-ports":
+		return checkNetworkPorts(ctx, pc, eo, res)
+
+	case "network-ip-availability":
+		return checkNetworkIPAvailability(ctx, pc, eo, res)
+
+	case "load-balancer":
+		return checkLoadBalancer(ctx, pc, eo, res)
+
+	case "dns":
+		return checkDNS(ctx, pc, eo, res)
+
+	case "container-infra":
+		return checkContainerInfra(ctx, pc, eo, res)
+
+	case "instance-ha":
+		return checkInstanceHA(ctx, pc, eo, res)
+
+	case "metric":
+		return checkMetric(ctx, pc, eo, res)
+
+	case "object-store":
+		return checkObjectStore(ctx, pc, eo, res)
+
+	case "image":
+		return checkImage(ctx, pc, eo, res)
+
+	case "catalog":
+		return checkCatalog(ctx, pc, res)
+
+	case "endpoints":
+		return checkEndpoints(ctx, pc, eo, tlsCfg, authLatency, res)
+
+	case "certificates":
+		return checkCertificates(ctx, pc, eo, tlsCfg, res)
+
+	case "canary":
+		return checkCanary(ctx, pc, eo, res)
+
+	default:
+		d, ok := plugin.drivers[service]
+		if !ok {
+			return fmt.Errorf("unsupported service: %s", service)
+		}
+
+		return checkServiceDriver(ctx, pc, eo, d, res)
+	}
+}
+
+// evaluateService sets the verdict of the service list record.
+// Reason is nil when the API reports no disabled reason for the service.
+func evaluateService(rec *Record, enabled, up bool, heartbeat time.Time, reason *string) {
+	rec.Status = &ServiceStatus{Enabled: enabled, Up: up, Heartbeat: heartbeat}
+
+	if enabled && !up {
+		rec.Fail(sensu.CheckStateCritical, "enabled service is down")
+	}
+
+	if !enabled && reason != nil && reasonMatch(*reason, plugin.CriticalDisabledReason) {
+		rec.Fail(sensu.CheckStateCritical, "disabled with critical reason")
+	}
+}
+
+func checkCompute(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewComputeV2(pc, eo)
+	if err != nil {
+		return err
+	}
+
+	pages, err := cptsrv.List(cli, nil).AllPages(ctx)
+	if err != nil {
+		return err
+	}
+
+	srvs, err := cptsrv.ExtractServices(pages)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(srvs, func(i, j int) bool {
+		si, sj := srvs[i], srvs[j]
+		return si.Binary < sj.Binary || (si.Binary == sj.Binary && si.Host < sj.Host)
+	})
+
+	t := res.AddTable("", "ID", "Binary", "Host", "Zone", "Status", "State", "Updated At", "Disabled Reason")
+
+	for _, srv := range srvs {
+		rec := t.Append(srv.ID, srv.Binary, srv.Host, srv.Zone, srv.Status, srv.State, srv.UpdatedAt, &srv.DisabledReason)
+		rec.ID, rec.Binary, rec.Host, rec.Zone = srv.ID, srv.Binary, srv.Host, srv.Zone
+
+		evaluateService(rec, srv.Status == "enabled", srv.State == "up", srv.UpdatedAt, &srv.DisabledReason)
+	}
+
+	return nil
+}
+
+func checkVolume(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewBlockStorageV3(pc, eo)
+	if err != nil {
+		return err
+	}
+
+	pages, err := volsrv.List(cli, nil).AllPages(ctx)
+	if err != nil {
+		return err
+	}
+
+	srvs, err := volsrv.ExtractServices(pages)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(srvs, func(i, j int) bool {
+		si, sj := srvs[i], srvs[j]
+		return si.Binary < sj.Binary || (si.Binary == sj.Binary && si.Host < sj.Host)
+	})
+
+	t := res.AddTable("", "Binary", "Host", "Zone", "Status", "State", "Updated At", "Disabled Reason")
+
+	for _, srv := range srvs {
+		rec := t.Append(srv.Binary, srv.Host, srv.Zone, srv.Status, srv.State, srv.UpdatedAt, &srv.DisabledReason)
+		rec.Binary, rec.Host, rec.Zone = srv.Binary, srv.Host, srv.Zone
+
+		evaluateService(rec, srv.Status == "enabled", srv.State == "up", srv.UpdatedAt, &srv.DisabledReason)
+	}
+
+	return nil
+}
+
+func checkShare(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewSharedFileSystemV2(pc, eo)
+	if err != nil {
+		return err
+	}
+
+	cli.Microversion = "2.7"
+
+	pages, err := sharesrv.List(cli, nil).AllPages(ctx)
+	if err != nil {
+		return err
+	}
+
+	srvs, err := sharesrv.ExtractServices(pages)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(srvs, func(i, j int) bool {
+		si, sj := srvs[i], srvs[j]
+		return si.Binary < sj.Binary || (si.Binary == sj.Binary && si.Host < sj.Host)
+	})
+
+	t := res.AddTable("", "ID", "Binary", "Host", "Zone", "Status", "State", "Updated At")
+
+	for _, srv := range srvs {
+		rec := t.Append(srv.ID, srv.Binary, srv.Host, srv.Zone, srv.Status, srv.State, srv.UpdatedAt)
+		rec.ID, rec.Binary, rec.Host, rec.Zone = fmt.Sprint(srv.ID), srv.Binary, srv.Host, srv.Zone
+
+		// manila has no disabled reason in 2.7
+		evaluateService(rec, srv.Status == "enabled", srv.State == "up", srv.UpdatedAt, nil)
+	}
+
+	return nil
+}
+
+func checkNetwork(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewNetworkV2(pc, eo)
+	if err != nil {
+		return err
+	}
+
+	pages, err := NeutronAgentList(cli, nil).AllPages(ctx)
+	if err != nil {
+		return fmt.Errorf("List error: %w", err)
+	}
+
+	agents, err := ExtractNeutronAgents(pages)
+	if err != nil {
+		return fmt.Errorf("Unmarshal error: %w", err)
+	}
+
+	sort.Slice(agents, func(i, j int) bool {
+		ai, aj := agents[i], agents[j]
+		return ai.AgentType < aj.AgentType || (ai.AgentType == aj.AgentType && ai.Host < aj.Host)
+	})
+
+	t := res.AddTable("", "ID", "Agent Type", "Host", "Availability Zone", "Alive", "State", "Binary", "Heartbeat")
+
+	for _, ag := range agents {
+		rec := t.Append(ag.ID, ag.AgentType, ag.Host, ag.AvailabilityZone, ag.Alive, ag.AdminStateUp, ag.Binary, ag.HeartbeatTimestamp)
+		rec.ID, rec.Binary, rec.Host, rec.Zone = ag.ID, ag.Binary, ag.Host, ag.AvailabilityZone
+
+		// agents have no disabled reason
+		evaluateService(rec, ag.AdminStateUp, ag.Alive, ag.HeartbeatTimestamp, nil)
+	}
+
+	if plugin.DHCPAgentsPerNetwork > 0 {
+		err := checkDHCPAgents(ctx, cli, agents, res)
+		if err != nil {
+			return err
+		}
+	}
+
+	if plugin.OVNMinGateways > 0 || plugin.OVNCheckMetadata {
+		checkOVNAgents(agents, res)
+	}
+
+	if plugin.ConfigDrift {
+		checkAgentConfigDrift(agents, res)
+	}
+
+	return nil
+}
+
+func checkAgentConfigDrift(agents []NeutronAgent, res *Result) {
+	drifts := agentConfigDrift(agents, plugin.DriftBaseline)
+	if len(drifts) == 0 {
+		res.Printf("No agent configuration drift found")
+		return
+	}
+
+	t := res.AddProblemTable("Agent configuration drift", "Agent Type", "Host", "Key", "Value", "Expected")
+
+	for _, d := range drifts {
+		rec := t.Append(d.AgentType, d.Host, d.Key, d.Value, d.Expected)
+		rec.Host = d.Host
+		rec.Fail(sensu.CheckStateWarning, d.Key+" differs")
+	}
+}
+
+func checkOVNAgents(agents []NeutronAgent, res *Result) {
+	st := ovnAgentsState(agents)
+
+	if plugin.OVNMinGateways > 0 {
+		state := sensu.CheckStateOK
+		if len(st.AliveGateways) < plugin.OVNMinGateways {
+			state = sensu.CheckStateCritical
+		}
+
+		res.Fail(state, "OVN gateway chassis alive: %d of %d, required: %d", len(st.AliveGateways), st.Gateways, plugin.OVNMinGateways)
+	}
+
+	if plugin.OVNCheckMetadata {
+		if len(st.NoMetadata) == 0 {
+			res.Printf("All %d OVN controller chassis have alive metadata agent", st.Controllers)
+		} else {
+			// records keep the chassis problems on the host entities in the proxy checks
+			t := res.AddProblemTable("OVN controller chassis without alive metadata agent", "Host")
+			for _, host := range st.NoMetadata {
+				rec := t.Append(host)
+				rec.Host = host
+				rec.Fail(sensu.CheckStateCritical, "no alive metadata agent")
+			}
+		}
+	}
+}
+
+func checkDHCPAgents(ctx context.Context, cli *gophercloud.ServiceClient, agents []NeutronAgent, res *Result) error {
+	enableDHCP := true
+	pages, err := subnets.List(cli, subnets.ListOpts{EnableDHCP: &enableDHCP}).AllPages(ctx)
+	if err != nil {
+		return fmt.Errorf("Subnet list error: %w", err)
+	}
+
+	subs, err := subnets.ExtractSubnets(pages)
+	if err != nil {
+		return fmt.Errorf("Subnet unmarshal error: %w", err)
+	}
+
+	pages, err = networks.List(cli, nil).AllPages(ctx)
+	if err != nil {
+		return fmt.Errorf("Network list error: %w", err)
+	}
+
+	nets, err := networks.ExtractNetworks(pages)
+	if err != nil {
+		return fmt.Errorf("Network unmarshal error: %w", err)
+	}
+
+	hosting := make(map[string][]string)
+	for _, ag := range agents {
+		if ag.AgentType != "DHCP agent" || !ag.AdminStateUp || !ag.Alive {
+			continue
+		}
+
+		agNets, err := netagents.ListDHCPNetworks(ctx, cli, ag.ID).Extract()
+		if err != nil {
+			return fmt.Errorf("DHCP agent %s network list error: %w", ag.Host, err)
+		}
+
+		for _, n := range agNets {
+			hosting[n.ID] = append(hosting[n.ID], ag.Host)
+		}
+	}
+
+	lacking := dhcpLackingNetworks(subs, nets, hosting, plugin.DHCPAgentsPerNetwork)
+	if len(lacking) == 0 {
+		res.Printf("All %d networks with DHCP-enabled subnets hosted by at least %d alive DHCP agents", len(dhcpNetworkIDs(subs)), plugin.DHCPAgentsPerNetwork)
+		return nil
+	}
+
+	t := res.AddProblemTable(fmt.Sprintf("Networks with less than %d alive DHCP agents", plugin.DHCPAgentsPerNetwork), "Network ID", "Name", "Project", "Agents", "Hosts")
+
+	for _, n := range lacking {
+		hosts := hosting[n.ID]
+		rec := t.Append(n.ID, n.Name, n.ProjectID, len(hosts), strings.Join(hosts, " "))
+		rec.ID = n.ID
+		rec.Fail(sensu.CheckStateCritical, fmt.Sprintf("hosted by %d alive DHCP agents", len(hosts)))
+	}
+
+	return nil
+}
+
+func checkBaremetal(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewBareMetalV1(pc, eo)
+	if err != nil {
+		return err
+	}
+	cli.Microversion = "1.49"
+
+	pages, err := conductors.List(cli, conductors.ListOpts{Detail: true}).AllPages(ctx)
+	if err != nil {
+		return err
+	}
+
+	srvs, err := conductors.ExtractConductors(pages)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(srvs, func(i, j int) bool {
+		si, sj := srvs[i], srvs[j]
+		return si.Hostname < sj.Hostname
+	})
+
+	t := res.AddTable("", "Host", "Conductor Group", "Drivers", "Alive", "Updated At")
+
+	for _, srv := range srvs {
+		rec := t.Append(srv.Hostname, srv.ConductorGroup, strings.Join(srv.Drivers, " "), srv.Alive, srv.UpdatedAt)
+		rec.Binary, rec.Host = "ironic-conductor", srv.Hostname
+
+		// conductors can not be disabled
+		evaluateService(rec, true, srv.Alive, srv.UpdatedAt, nil)
+	}
+
+	return nil
+}
+
+func checkNetworkPorts(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewNetworkV2(pc, eo)
+	if err != nil {
+		return err
+	}
+
+	cptCli, err := openstack.NewComputeV2(pc, eo)
+	if err != nil {
+		return err
+	}
+
+	pages, err := ports.List(cli, nil).AllPages(ctx)
+	if err != nil {
+		return fmt.Errorf("List error: %w", err)
+	}
+
+	nports, err := ExtractNeutronPorts(pages)
+	if err != nil {
+		return fmt.Errorf("Unmarshal error: %w", err)
+	}
+
+	sort.Slice(nports, func(i, j int) bool {
+		pi, pj := nports[i], nports[j]
+		return pi.HostID < pj.HostID || (pi.HostID == pj.HostID && pi.ID < pj.ID)
+	})
+
+	deadline := time.Now().Add(-plugin.gracePeriod)
+	downOnInstance := func(p NeutronPort) bool {
+		return p.Status == "DOWN" && strings.HasPrefix(p.DeviceOwner, "compute:") && p.UpdatedAt.Before(deadline)
+	}
+
+	// one list instead of server get per port, as network outage makes hundreds of ports down
+	activeServers := make(map[string]bool)
+	if slices.ContainsFunc(nports, downOnInstance) {
+		pages, err := servers.List(cptCli, servers.ListOpts{AllTenants: true, Status: "ACTIVE"}).AllPages(ctx)
+		if err != nil {
+			return fmt.Errorf("Server list error: %w", err)
+		}
+
+		srvs, err := servers.ExtractServers(pages)
+		if err != nil {
+			return fmt.Errorf("Server unmarshal error: %w", err)
+		}
+
+		for _, srv := range srvs {
+			activeServers[srv.ID] = srv.Status == "ACTIVE"
+		}
+	}
+
+	t := res.AddProblemTable("", "Host", "ID", "Device Owner", "Device ID", "VIF Type", "Status", "Updated At", "Problem")
+
+	for _, p := range nports {
+		problem := ""
+
+		switch {
+		case portBindingFailed(p, plugin.IgnoreDeviceOwner):
+			problem = "binding " + p.VIFType
+
+		case downOnInstance(p) && activeServers[p.DeviceID]:
+			problem = "down on active instance"
+		}
+
+		if problem == "" {
+			continue
+		}
+
+		rec := t.Append(p.HostID, p.ID, p.DeviceOwner, p.DeviceID, p.VIFType, p.Status, p.UpdatedAt, problem)
+		rec.ID, rec.Host = p.ID, p.HostID
+		rec.Fail(sensu.CheckStateCritical, problem)
+	}
+
+	if len(t.Records) == 0 {
+		res.Printf("All %d ports are bound", len(nports))
+	}
+
+	return nil
+}
+
+func checkNetworkIPAvailability(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewNetworkV2(pc, eo)
+	if err != nil {
+		return err
+	}
+
+	selected := make(map[string]bool)
+	selectNetworks := func(opts networks.ListOptsBuilder) error {
+		pages, err := networks.List(cli, opts).AllPages(ctx)
+		if err != nil {
+			return fmt.Errorf("Network list error: %w", err)
+		}
+
+		nets, err := networks.ExtractNetworks(pages)
+		if err != nil {
+			return fmt.Errorf("Network unmarshal error: %w", err)
+		}
+
+		for _, n := range nets {
+			selected[n.ID] = true
+		}
+		return nil
+	}
+
+	if plugin.IPExternal {
+		isExternal := true
+		err = selectNetworks(external.ListOptsExt{ListOptsBuilder: networks.ListOpts{}, External: &isExternal})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(plugin.IPNetworkTags) > 0 {
+		err = selectNetworks(networks.ListOpts{TagsAny: strings.Join(plugin.IPNetworkTags, ",")})
+		if err != nil {
+			return err
+		}
+	}
+
+	pages, err := ipavail.List(cli, nil).AllPages(ctx)
+	if err != nil {
+		return fmt.Errorf("List error: %w", err)
+	}
+
+	avails, err := ipavail.ExtractNetworkIPAvailabilities(pages)
+	if err != nil {
+		return fmt.Errorf("Unmarshal error: %w", err)
+	}
+
+	sort.Slice(avails, func(i, j int) bool {
+		ai, aj := avails[i], avails[j]
+		return ai.NetworkName < aj.NetworkName || (ai.NetworkName == aj.NetworkName && ai.NetworkID < aj.NetworkID)
+	})
+
+	t := res.AddTable("", "Network", "Subnet", "CIDR", "Total IPs", "Used IPs", "Used %")
+
+	for _, av := range avails {
+		if !selected[av.NetworkID] && !slices.Contains(plugin.IPNetworks, av.NetworkName) {
+			continue
+		}
+
+		for _, sub := range av.SubnetIPAvailabilities {
+			used, err := subnetIPUsage(sub)
+			if err != nil {
+				return fmt.Errorf("Subnet %s: %w", sub.SubnetID, err)
+			}
+
+			rec := t.Append(av.NetworkName, sub.SubnetName, sub.CIDR, sub.TotalIPs, sub.UsedIPs, fmt.Sprintf("%.1f", used))
+			rec.ID = sub.SubnetID
+
+			if used >= plugin.IPCritical {
+				rec.Fail(sensu.CheckStateCritical, fmt.Sprintf("used %.1f%% of IPs", used))
+			} else if used >= plugin.IPWarning {
+				rec.Fail(sensu.CheckStateWarning, fmt.Sprintf("used %.1f%% of IPs", used))
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkLoadBalancer(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewLoadBalancerV2(pc, eo)
+	if err != nil {
+		return err
+	}
+
+	pages, err := amphorae.List(cli, nil).AllPages(ctx)
+	if err != nil {
+		return fmt.Errorf("Amphora list error: %w", err)
+	}
+
+	amps, err := amphorae.ExtractAmphorae(pages)
+	if err != nil {
+		return fmt.Errorf("Amphora unmarshal error: %w", err)
+	}
+
+	pages, err = loadbalancers.List(cli, nil).AllPages(ctx)
+	if err != nil {
+		return fmt.Errorf("Load balancer list error: %w", err)
+	}
+
+	lbs, err := loadbalancers.ExtractLoadBalancers(pages)
+	if err != nil {
+		return fmt.Errorf("Load balancer unmarshal error: %w", err)
+	}
+
+	sort.Slice(amps, func(i, j int) bool {
+		ai, aj := amps[i], amps[j]
+		return ai.LoadbalancerID < aj.LoadbalancerID || (ai.LoadbalancerID == aj.LoadbalancerID && ai.ID < aj.ID)
+	})
+
+	sort.Slice(lbs, func(i, j int) bool {
+		li, lj := lbs[i], lbs[j]
+		return li.Name < lj.Name || (li.Name == lj.Name && li.ID < lj.ID)
+	})
+
+	now := time.Now()
+	deadline := now.Add(-plugin.gracePeriod)
+
+	lbByID := make(map[string]loadbalancers.LoadBalancer, len(lbs))
+	for _, lb := range lbs {
+		lbByID[lb.ID] = lb
+	}
+
+	t := res.AddProblemTable("Amphorae", "ID", "Load Balancer ID", "Compute ID", "Role", "Status", "Updated At", "Problem")
+
+	for _, a := range amps {
+		state := sensu.CheckStateCritical
+		problem := amphoraProblem(a, deadline)
+		if problem == "" {
+			// failover recovers the load balancer, but lost heartbeats mean broken amphora or lb-mgmt-net
+			state = sensu.CheckStateWarning
+			problem = amphoraHeartbeatLost(a, lbByID, now.Add(-plugin.timeWindow))
+		}
+		if problem == "" {
+			continue
+		}
 
-	return sensu.CheckStateOK, nil
-}
+		rec := t.Append(a.ID, a.LoadbalancerID, a.ComputeID, a.Role, a.Status, a.UpdatedAt, problem)
+		rec.ID = a.ID
+		rec.Fail(state, problem)
+	}
 
-func executeCheck(event *corev2.Event) (int, error) {
-	ctx, cf := context.WithTimeout(context.Background(), 1*time.Minute)
-	defer cf()
+	t = res.AddProblemTable("Load Balancers", "ID", "Name", "Project", "Provider", "Provisioning Status", "Operating Status", "Updated At", "Problem")
 
-	var httpCli *http.Client
-	if plugin.Debug {
-		httpCli = &http.Client{
-			Transport: &oscli.RoundTripper{
-				Rt:     &http.Transport{},
-				Logger: &oscli.DefaultLogger{},
-			},
+	for _, lb := range lbs {
+		problem := loadBalancerProblem(lb, deadline)
+		if problem == "" {
+			continue
 		}
-	} else {
-		httpCli = &http.Client{Transport: &http.Transport{}}
+
+		rec := t.Append(lb.ID, lb.Name, lb.ProjectID, lb.Provider, lb.ProvisioningStatus, lb.OperatingStatus, lb.UpdatedAt, problem)
+		rec.ID = lb.ID
+		rec.Fail(sensu.CheckStateCritical, problem)
 	}
 
-	pOpts := []clouds.ParseOption{clouds.WithCloudName(plugin.Cloud)}
-	if plugin.CloudsFile != "" {
-		pOpts = append(pOpts, clouds.WithLocations(plugin.CloudsFile))
+	if res.Evaluate() == sensu.CheckStateOK {
+		res.Printf("All %d amphorae and %d load balancers are healthy", len(amps), len(lbs))
 	}
 
-	ao, eo, tlsCfg, err := clouds.Parse(pOpts...)
+	return nil
+}
+
+func checkDNS(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewDNSV2(pc, eo)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
 
-	// check never need to reauth
-	ao.AllowReauth = false
+	pages, err := DesignateServiceStatusList(cli).AllPages(ctx)
+	if err != nil {
+		return err
+	}
 
-	pc, err := config.NewProviderClient(ctx, ao, config.WithHTTPClient(*httpCli), config.WithTLSConfig(tlsCfg))
+	srvs, err := ExtractDesignateServiceStatuses(pages)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
 
-	switch plugin.Service {
-	case "compute":
-		return checkCompute(ctx, pc, eo)
+	sort.Slice(srvs, func(i, j int) bool {
+		si, sj := srvs[i], srvs[j]
+		return si.ServiceName < sj.ServiceName || (si.ServiceName == sj.ServiceName && si.Hostname < sj.Hostname)
+	})
 
-	case "volume":
-		return checkVolume(ctx, pc, eo)
+	t := res.AddTable("", "ID", "Service", "Host", "Status", "Heartbeat")
 
-	case "sharev2":
-		return checkShare(ctx, pc, eo)
+	for _, srv := range srvs {
+		rec := t.Append(srv.ID, srv.ServiceName, srv.Hostname, srv.Status, srv.HeartbeatedAt.As())
+		rec.ID, rec.Binary, rec.Host = srv.ID, srv.ServiceName, srv.Hostname
 
-	case "network":
-		return checkNetwork(ctx, pc, eo)
+		// designate services can not be disabled
+		evaluateService(rec, true, srv.Status == "UP" || srv.Status == "WARNING", srv.HeartbeatedAt.As(), nil)
 
-	case "orchestration":
-		return checkOrchestration(ctx, pc, eo)
+		if srv.Status == "WARNING" {
+			rec.Fail(sensu.CheckStateWarning, "service reports warning")
+		}
+	}
 
-	case "container":
-		return checkContainer(ctx, pc, eo)
+	// zones of all projects
+	cli.MoreHeaders = map[string]string{"X-Auth-All-Projects": "true"}
 
-	case "clustering":
-		return checkClustering(ctx, pc, eo)
+	deadline := time.Now().Add(-plugin.gracePeriod)
 
-	case "baremetal":
-		return checkBaremetal(ctx, pc, eo)
+	t = res.AddProblemTable("Stuck zones", "ID", "Name", "Project", "Pool", "Status", "Action", "Updated At")
 
-	default:
-		return sensu.CheckStateUnknown, fmt.Errorf("unsupported service: %s", plugin.Service)
+	for _, status := range []string{"PENDING", "ERROR"} {
+		pages, err := zones.List(cli, zones.ListOpts{Status: status}).AllPages(ctx)
+		if err != nil {
+			return fmt.Errorf("Zone list error: %w", err)
+		}
+
+		zs, err := zones.ExtractZones(pages)
+		if err != nil {
+			return fmt.Errorf("Zone unmarshal error: %w", err)
+		}
+
+		for _, z := range zs {
+			if !zoneStuck(z, deadline) {
+				continue
+			}
+
+			rec := t.Append(z.ID, z.Name, z.ProjectID, z.PoolID, z.Status, z.Action, z.UpdatedAt)
+			rec.ID = z.ID
+			rec.Fail(sensu.CheckStateCritical, "zone stuck in "+z.Status)
+		}
 	}
+
+	return nil
 }
 
-func checkCompute(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (int, error) {
-	cli, err := openstack.NewComputeV2(pc, eo)
+func checkContainerInfra(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewContainerInfraV1(pc, eo)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
 
-	pages, err := cptsrv.List(cli, nil).AllPages(ctx)
+	pages, err := MagnumServiceList(cli).AllPages(ctx)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
 
-	srvs, err := cptsrv.ExtractServices(pages)
+	srvs, err := ExtractMagnumServices(pages)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
 
 	sort.Slice(srvs, func(i, j int) bool {
@@ -213,89 +1590,163 @@ func checkCompute(ctx context.Context, pc *gophercloud.ProviderClient, eo gopher
 		return si.Binary < sj.Binary || (si.Binary == sj.Binary && si.Host < sj.Host)
 	})
 
-	ret := sensu.CheckStateOK
-
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"ID", "Binary", "Host", "Zone", "Status", "State", "Updated At", "Disabled Reason"})
+	t := res.AddTable("", "ID", "Binary", "Host", "Disabled", "State", "Updated At", "Disabled Reason")
 
 	for _, srv := range srvs {
-		t.AppendRow(table.Row{srv.ID, srv.Binary, srv.Host, srv.Zone, srv.Status, srv.State, srv.UpdatedAt, srv.DisabledReason})
+		rec := t.Append(srv.ID, srv.Binary, srv.Host, srv.Disabled, srv.State, srv.UpdatedAt.As(), &srv.DisabledReason)
+		rec.ID, rec.Binary, rec.Host = fmt.Sprint(srv.ID), srv.Binary, srv.Host
 
-		if srv.Status == "enabled" && srv.State != "up" {
-			ret = sensu.CheckStateCritical
-		}
+		evaluateService(rec, !srv.Disabled, srv.State == "up", srv.UpdatedAt.As(), &srv.DisabledReason)
+	}
+
+	pages, err = clusters.List(cli, nil).AllPages(ctx)
+	if err != nil {
+		return fmt.Errorf("Cluster list error: %w", err)
+	}
+
+	cls, err := clusters.ExtractClusters(pages)
+	if err != nil {
+		return fmt.Errorf("Cluster unmarshal error: %w", err)
+	}
 
-		if srv.Status == "disabled" && reasonMatch(srv.DisabledReason, plugin.CriticalDisabledReason) {
-			ret = sensu.CheckStateCritical
+	sort.Slice(cls, func(i, j int) bool {
+		ci, cj := cls[i], cls[j]
+		return ci.Name < cj.Name || (ci.Name == cj.Name && ci.UUID < cj.UUID)
+	})
+
+	deadline := time.Now().Add(-plugin.gracePeriod)
+	counts := make(map[string]int)
+
+	t = res.AddProblemTable("Failed and stuck clusters", "UUID", "Name", "Project", "Status", "Updated At", "Status Reason")
+
+	for _, c := range cls {
+		problem := clusterProblem(c, deadline)
+		if problem == "" {
+			continue
 		}
+
+		counts[problem]++
+		rec := t.Append(c.UUID, c.Name, c.ProjectID, c.Status, c.UpdatedAt, c.StatusReason)
+		rec.ID = c.UUID
+		rec.Fail(sensu.CheckStateWarning, "cluster "+problem)
 	}
 
-	t.Render()
+	res.Printf("Clusters: %d total, %d failed, %d stuck", len(cls), counts["failed"], counts["stuck"])
 
-	return ret, nil
+	return nil
 }
 
-func checkVolume(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (int, error) {
-	cli, err := openstack.NewBlockStorageV3(pc, eo)
+func checkInstanceHA(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := NewInstanceHAV1(pc, eo)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
+	cli.Microversion = "1.2"
 
-	pages, err := volsrv.List(cli, nil).AllPages(ctx)
+	pages, err := MasakariSegmentList(cli).AllPages(ctx)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return fmt.Errorf("Segment list error: %w", err)
 	}
 
-	srvs, err := volsrv.ExtractServices(pages)
+	segs, err := ExtractMasakariSegments(pages)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return fmt.Errorf("Segment unmarshal error: %w", err)
 	}
 
-	sort.Slice(srvs, func(i, j int) bool {
-		si, sj := srvs[i], srvs[j]
-		return si.Binary < sj.Binary || (si.Binary == sj.Binary && si.Host < sj.Host)
+	sort.Slice(segs, func(i, j int) bool {
+		return segs[i].Name < segs[j].Name
 	})
 
-	ret := sensu.CheckStateOK
+	pages, err = MasakariNotificationList(cli, time.Now().Add(-plugin.timeWindow)).AllPages(ctx)
+	if err != nil {
+		return fmt.Errorf("Notification list error: %w", err)
+	}
+
+	notifs, err := ExtractMasakariNotifications(pages)
+	if err != nil {
+		return fmt.Errorf("Notification unmarshal error: %w", err)
+	}
 
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Binary", "Host", "Zone", "Status", "State", "Updated At", "Disabled Reason"})
+	recovered := masakariRecoveredHosts(notifs)
 
-	for _, srv := range srvs {
-		t.AppendRow(table.Row{srv.Binary, srv.Host, srv.Zone, srv.Status, srv.State, srv.UpdatedAt, srv.DisabledReason})
+	t := res.AddTable("", "Segment", "Recovery Method", "Enabled", "Host", "Type", "Reserved", "On Maintenance", "Updated At")
 
-		if srv.Status == "enabled" && srv.State != "up" {
-			ret = sensu.CheckStateCritical
+	for _, seg := range segs {
+		enabled := seg.Enabled == nil || *seg.Enabled
+
+		pages, err := MasakariHostList(cli, seg.UUID).AllPages(ctx)
+		if err != nil {
+			return fmt.Errorf("Segment %s host list error: %w", seg.Name, err)
 		}
 
-		if srv.Status == "disabled" && reasonMatch(srv.DisabledReason, plugin.CriticalDisabledReason) {
-			ret = sensu.CheckStateCritical
+		hosts, err := ExtractMasakariHosts(pages)
+		if err != nil {
+			return fmt.Errorf("Segment %s host unmarshal error: %w", seg.Name, err)
 		}
-	}
 
-	t.Render()
+		sort.Slice(hosts, func(i, j int) bool {
+			return hosts[i].Name < hosts[j].Name
+		})
 
-	return ret, nil
-}
+		if len(hosts) == 0 {
+			rec := t.Append(seg.Name, seg.RecoveryMethod, enabled)
+			rec.ID = seg.UUID
+			if !enabled {
+				rec.Fail(sensu.CheckStateCritical, "segment disabled")
+			}
+		}
 
-func checkShare(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (int, error) {
-	cli, err := openstack.NewSharedFileSystemV2(pc, eo)
-	if err != nil {
-		return sensu.CheckStateUnknown, err
+		for _, h := range hosts {
+			rec := t.Append(seg.Name, seg.RecoveryMethod, enabled, h.Name, h.Type, h.Reserved, h.OnMaintenance, h.UpdatedAt.As())
+			rec.ID, rec.Host = h.UUID, h.Name
+
+			// recovery may be older than the time window, the host stays on maintenance until reset
+			if h.OnMaintenance && !recovered[h.Name] {
+				pages, err := MasakariHostNotificationList(cli, h.UUID).AllPages(ctx)
+				if err != nil {
+					return fmt.Errorf("Host %s notification list error: %w", h.Name, err)
+				}
+
+				last, err := ExtractMasakariNotifications(pages)
+				if err != nil {
+					return fmt.Errorf("Host %s notification unmarshal error: %w", h.Name, err)
+				}
+
+				maps.Copy(recovered, masakariRecoveredHosts(last))
+			}
+
+			for _, problem := range masakariHostProblems(h, enabled, recovered) {
+				rec.Fail(sensu.CheckStateCritical, problem)
+			}
+		}
 	}
 
-	cli.Microversion = "2.7"
+	sort.Slice(notifs, func(i, j int) bool {
+		return notifs[i].GeneratedTime.As().Before(notifs[j].GeneratedTime.As())
+	})
 
-	pages, err := sharesrv.List(cli, nil).AllPages(ctx)
-	if err != nil {
-		return sensu.CheckStateUnknown, err
+	deadline := time.Now().Add(-plugin.gracePeriod)
+
+	t = res.AddProblemTable("Unfinished notifications", "UUID", "Type", "Host", "Status", "Generated At", "Updated At")
+
+	for _, n := range notifs {
+		problem := masakariNotificationProblem(n, deadline)
+		if problem == "" {
+			continue
+		}
+
+		rec := t.Append(n.NotificationUUID, n.Type, n.Hostname, n.Status, n.GeneratedTime.As(), n.UpdatedAt.As())
+		rec.ID, rec.Host = n.NotificationUUID, n.Hostname
+		rec.Fail(sensu.CheckStateCritical, problem)
 	}
 
-	srvs, err := sharesrv.ExtractServices(pages)
+	return nil
+}
+
+func checkServiceDriver(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, d ServiceDriver, res *Result) error {
+	srvs, err := d.List(ctx, pc, eo)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
 
 	sort.Slice(srvs, func(i, j int) bool {
@@ -303,231 +1754,472 @@ func checkShare(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercl
 		return si.Binary < sj.Binary || (si.Binary == sj.Binary && si.Host < sj.Host)
 	})
 
-	ret := sensu.CheckStateOK
-
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"ID", "Binary", "Host", "Zone", "Status", "State", "Updated At"})
+	t := res.AddTable("", "ID", "Binary", "Host", "Zone", "Enabled", "State", "Updated At", "Heartbeat", "Disabled Reason")
 
 	for _, srv := range srvs {
-		t.AppendRow(table.Row{srv.ID, srv.Binary, srv.Host, srv.Zone, srv.Status, srv.State, srv.UpdatedAt})
+		enabled := d.Enabled(srv)
+		rec := t.Append(srv.ID, srv.Binary, srv.Host, srv.Zone, enabled, srv.State, srv.UpdatedAt, srv.Heartbeat, srv.Reason)
+		rec.ID, rec.Binary, rec.Host, rec.Zone = srv.ID, srv.Binary, srv.Host, srv.Zone
 
-		if srv.Status == "enabled" && srv.State != "up" {
-			ret = sensu.CheckStateCritical
+		heartbeat := srv.Heartbeat
+		if heartbeat.IsZero() {
+			heartbeat = srv.UpdatedAt
+		}
+
+		var reason *string
+		if d.Fields.Reason != "" {
+			reason = &srv.Reason
 		}
-	}
 
-	t.Render()
+		evaluateService(rec, enabled, d.Up(srv), heartbeat, reason)
+	}
 
-	return ret, nil
+	return nil
 }
 
-func checkNetwork(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (int, error) {
-	cli, err := openstack.NewNetworkV2(pc, eo)
+func checkMetric(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := NewServiceClient(pc, eo, "metric")
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
 
-	pages, err := NeutronAgentList(cli, nil).AllPages(ctx)
+	st, err := GnocchiStatusGet(ctx, cli)
 	if err != nil {
-		return sensu.CheckStateUnknown, fmt.Errorf("List error: %w", err)
+		return err
 	}
 
-	agents, err := ExtractNeutronAgents(pages)
+	t := res.AddTable("", "Parameter", "Value", "Threshold")
+	evaluateGnocchiStatus(t, st, plugin.MetricMaxMeasures, plugin.MetricMaxMetrics, plugin.MetricMinProcessors)
+
+	return nil
+}
+
+func checkObjectStore(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	catalog, err := ServiceCatalog(pc)
 	if err != nil {
-		return sensu.CheckStateUnknown, fmt.Errorf("Unmarshal error: %w", err)
+		return err
 	}
 
-	sort.Slice(agents, func(i, j int) bool {
-		ai, aj := agents[i], agents[j]
-		return ai.AgentType < aj.AgentType || (ai.AgentType == aj.AgentType && ai.Host < aj.Host)
-	})
+	proxies := make([]string, 0)
+	for _, ep := range catalogEndpoints(catalog, "object-store", eo.Region) {
+		proxyURL, err := swiftProxyURL(ep.URL)
+		if err != nil {
+			return fmt.Errorf("%s endpoint %s: %w", ep.Interface, ep.URL, err)
+		}
+
+		if !slices.Contains(proxies, proxyURL) {
+			proxies = append(proxies, proxyURL)
+		}
+	}
 
-	ret := sensu.CheckStateOK
+	if len(proxies) == 0 {
+		return fmt.Errorf("object-store endpoints not found in the catalog")
+	}
 
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"ID", "Agent Type", "Host", "Availability Zone", "Alive", "State", "Binary", "Heartbeat"})
+	sort.Strings(proxies)
 
-	for _, ag := range agents {
-		t.AppendRow(table.Row{ag.ID, ag.AgentType, ag.Host, ag.AvailabilityZone, ag.Alive, ag.AdminStateUp, ag.Binary, ag.HeartbeatTimestamp})
+	t := res.AddTable("", "Proxy", "Request", "Latency", "Error")
 
-		if ag.AdminStateUp && !ag.Alive {
-			ret = sensu.CheckStateCritical
+	for _, proxyURL := range proxies {
+		for _, path := range []string{"healthcheck", "info"} {
+			latency, err := swiftProbe(ctx, &pc.HTTPClient, proxyURL, path)
+			if err != nil {
+				rec := t.Append(proxyURL, path, latency, err)
+				rec.Fail(sensu.CheckStateCritical, "request failed")
+				continue
+			}
+
+			rec := t.Append(proxyURL, path, latency, "")
+			failLatency(rec, latencyState(latency))
 		}
 	}
 
-	t.Render()
+	if plugin.SwiftContainer != "" {
+		cli, err := openstack.NewObjectStorageV1(pc, eo)
+		if err != nil {
+			return err
+		}
+
+		hostname, _ := os.Hostname()
+		object := "sensu-probe-" + hostname
 
-	return ret, nil
-}
+		latencies, err := swiftRoundTrip(ctx, cli, plugin.SwiftContainer, object)
+		for _, method := range []string{"PUT", "GET", "DELETE"} {
+			latency, ok := latencies[method]
+			if !ok {
+				continue
+			}
 
-func checkOrchestration(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (int, error) {
-	cli, err := openstack.NewOrchestrationV1(pc, eo)
-	if err != nil {
-		return sensu.CheckStateUnknown, err
+			rec := t.Append(cli.Endpoint, method+" "+plugin.SwiftContainer+"/"+object, latency, "")
+			failLatency(rec, latencyState(latency))
+		}
+
+		if err != nil {
+			rec := t.Append(cli.Endpoint, "round trip", "", err)
+			rec.Fail(sensu.CheckStateCritical, "round trip failed")
+		}
 	}
 
-	pages, err := HeatServiceList(cli).AllPages(ctx)
-	if err != nil {
-		return sensu.CheckStateUnknown, err
+	return nil
+}
+
+func failLatency(rec *Record, state int) {
+	if state != sensu.CheckStateOK {
+		rec.Fail(state, "latency over threshold")
 	}
+}
 
-	srvs, err := ExtractHeatServices(pages)
+func checkImage(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	cli, err := openstack.NewImageV2(pc, eo)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
 
-	sort.Slice(srvs, func(i, j int) bool {
-		si, sj := srvs[i], srvs[j]
-		return si.Binary < sj.Binary || (si.Binary == sj.Binary && si.Host < sj.Host)
-	})
+	// store detail is absent without multi-store and allowed to admins only
+	stores, err := GlanceStoreList(ctx, cli)
+	if gophercloud.ResponseCodeIs(err, http.StatusNotFound) || gophercloud.ResponseCodeIs(err, http.StatusForbidden) {
+		res.Printf("Store detail is not available: %v", err)
+	} else if err != nil {
+		return fmt.Errorf("Store list error: %w", err)
+	}
 
-	ret := sensu.CheckStateOK
+	imgs := make([]images.Image, 0)
+	for _, status := range []images.ImageStatus{images.ImageStatusQueued, images.ImageStatusSaving, images.ImageStatusImporting, images.ImageStatusActive} {
+		opts := images.ListOpts{
+			Status:     status,
+			Visibility: images.ImageVisibility("all"),
+		}
 
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"ID", "Binary", "Host", "Status", "Report Interval", "Updated At"})
+		// failed imports are reported within the time window, stuck ones regardless of age
+		if status == images.ImageStatusQueued || status == images.ImageStatusActive {
+			opts.UpdatedAtQuery = &images.ImageDateQuery{Date: time.Now().Add(-plugin.timeWindow), Filter: images.FilterGTE}
+		}
 
-	for _, srv := range srvs {
-		t.AppendRow(table.Row{srv.ID, srv.Binary, srv.Host, srv.Status, srv.ReportInterval, srv.UpdatedAt.As()})
+		pages, err := images.List(cli, opts).AllPages(ctx)
+		if err != nil {
+			return fmt.Errorf("Image list error: %w", err)
+		}
 
-		if srv.Status != "up" {
-			ret = sensu.CheckStateCritical
+		imgPage, err := images.ExtractImages(pages)
+		if err != nil {
+			return fmt.Errorf("Image unmarshal error: %w", err)
 		}
+
+		imgs = append(imgs, imgPage...)
 	}
 
-	t.Render()
+	now := time.Now()
+	imports := imageStoreImports(imgs, now.Add(-plugin.gracePeriod), now.Add(-plugin.timeWindow))
 
-	return ret, nil
-}
+	if len(stores) > 0 {
+		sort.Slice(stores, func(i, j int) bool {
+			return stores[i].ID < stores[j].ID
+		})
 
-func checkContainer(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (int, error) {
-	cli, err := openstack.NewContainerV1(pc, eo)
-	if err != nil {
-		return sensu.CheckStateUnknown, err
-	}
+		t := res.AddTable("Stores", "ID", "Type", "Default", "Read Only", "Stuck Imports", "Failed Imports")
 
-	pages, err := ZunServiceList(cli).AllPages(ctx)
-	if err != nil {
-		return sensu.CheckStateUnknown, err
-	}
+		for _, st := range stores {
+			si, ok := imports[st.ID]
+			if !ok {
+				si = new(GlanceStoreImports)
+			}
 
-	srvs, err := ExtractZunServices(pages)
-	if err != nil {
-		return sensu.CheckStateUnknown, err
+			rec := t.Append(st.ID, st.Type, st.Default, st.ReadOnly, strings.Join(si.Importing, " "), strings.Join(si.Failed, " "))
+			rec.ID = st.ID
+
+			state, reason := si.State(plugin.ImageFailedImports)
+			if state != sensu.CheckStateOK {
+				rec.Fail(state, reason)
+			}
+		}
 	}
 
-	sort.Slice(srvs, func(i, j int) bool {
-		si, sj := srvs[i], srvs[j]
-		return si.Binary < sj.Binary || (si.Binary == sj.Binary && si.Host < sj.Host)
-	})
+	t := res.AddProblemTable("Stuck and failed images", "ID", "Name", "Owner", "Status", "Updated At")
+
+	for _, img := range imgs {
+		stuck := (img.Status == images.ImageStatusSaving || img.Status == images.ImageStatusImporting) && img.UpdatedAt.Before(now.Add(-plugin.gracePeriod))
+		if !stuck && len(imagePropertyList(img, "os_glance_failed_import")) == 0 {
+			continue
+		}
 
-	ret := sensu.CheckStateOK
+		rec := t.Append(img.ID, img.Name, img.Owner, img.Status, img.UpdatedAt)
+		rec.ID = img.ID
+		if stuck {
+			rec.Fail(sensu.CheckStateCritical, "image stuck in "+string(img.Status))
+		} else {
+			// single failures are usually bad uploads, stores are evaluated by the failures count
+			rec.Fail(sensu.CheckStateWarning, "image import failed")
+		}
+	}
 
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"ID", "Binary", "Host", "Availability Zone", "Disabled", "State", "Updated At", "Heartbeat", "Disable Reason"})
+	t = res.AddProblemTable("Stuck and failed tasks", "ID", "Type", "Owner", "Status", "Updated At", "Message")
 
-	for _, srv := range srvs {
-		t.AppendRow(table.Row{srv.ID, srv.Binary, srv.Host, srv.AvailabilityZone, srv.Disabled, srv.State, srv.UpdatedAt.As(), srv.LastSeenUp.As(), srv.DisableReason})
+	for _, status := range []tasks.TaskStatus{tasks.TaskStatusProcessing, tasks.TaskStatusFailure} {
+		pages, err := tasks.List(cli, tasks.ListOpts{Status: status}).AllPages(ctx)
+		if err != nil {
+			return fmt.Errorf("Task list error: %w", err)
+		}
 
-		if !srv.Disabled && srv.State != "up" {
-			ret = sensu.CheckStateCritical
+		tks, err := tasks.ExtractTasks(pages)
+		if err != nil {
+			return fmt.Errorf("Task unmarshal error: %w", err)
 		}
 
-		if srv.Disabled && reasonMatch(srv.DisableReason, plugin.CriticalDisabledReason) {
-			ret = sensu.CheckStateCritical
+		for _, tk := range tks {
+			switch {
+			case status == tasks.TaskStatusProcessing && tk.UpdatedAt.Before(now.Add(-plugin.gracePeriod)):
+			case status == tasks.TaskStatusFailure && tk.UpdatedAt.After(now.Add(-plugin.timeWindow)):
+			default:
+				continue
+			}
+
+			rec := t.Append(tk.ID, tk.Type, tk.Owner, tk.Status, tk.UpdatedAt, tk.Message)
+			rec.ID = tk.ID
+			rec.Fail(sensu.CheckStateCritical, "task "+tk.Status)
 		}
 	}
 
-	t.Render()
+	if res.Evaluate() == sensu.CheckStateOK {
+		res.Printf("No stuck or failed image imports and tasks within %s", plugin.timeWindow)
+	}
 
-	return ret, nil
+	return nil
 }
 
-func checkClustering(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (int, error) {
-	cli, err := NewClusteringV1(pc, eo)
+func checkCatalog(ctx context.Context, pc *gophercloud.ProviderClient, res *Result) error {
+	catalog, err := ServiceCatalog(pc)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
-	cli.Microversion = "1.7"
 
-	pages, err := SenlinServiceList(cli).AllPages(ctx)
-	if err != nil {
-		return sensu.CheckStateUnknown, err
+	regions := plugin.CatalogRegions
+	if len(regions) == 0 {
+		regions = catalogRegions(catalog)
+	}
+
+	problems := validateCatalog(catalog, regions, plugin.CatalogInterfaces)
+
+	resolved := make(map[string]error)
+	for _, entry := range catalog.Entries {
+		for _, ep := range entry.Endpoints {
+			u, err := url.Parse(ep.URL)
+			if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+				continue
+			}
+
+			host := u.Hostname()
+			if _, ok := resolved[host]; !ok {
+				_, resolved[host] = net.DefaultResolver.LookupHost(ctx, host)
+			}
+
+			if err := resolved[host]; err != nil {
+				problems = append(problems, CatalogProblem{entry.Type, ep.Region, ep.Interface, ep.URL, err.Error(), sensu.CheckStateCritical})
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		res.Printf("All %d catalog services have valid %s endpoints in regions: %s", len(catalog.Entries), strings.Join(plugin.CatalogInterfaces, ", "), strings.Join(regions, ", "))
+		return nil
+	}
+
+	t := res.AddProblemTable("", "Service", "Region", "Interface", "URL", "Problem")
+
+	for _, p := range problems {
+		rec := t.Append(p.Service, p.Region, p.Interface, p.URL, p.Problem)
+		rec.Fail(p.State, p.Problem)
 	}
 
-	srvs, err := ExtractSenlinServices(pages)
+	return nil
+}
+
+func checkEndpoints(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, tlsCfg *tls.Config, authLatency time.Duration, res *Result) error {
+	catalog, err := ServiceCatalog(pc)
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
 
-	sort.Slice(srvs, func(i, j int) bool {
-		si, sj := srvs[i], srvs[j]
-		return si.Binary < sj.Binary || (si.Binary == sj.Binary && si.Host < sj.Host)
+	t := res.AddTable("", "Service", "Interface", "URL", "Status", "DNS", "Connect", "TLS", "Response", "Total", "Error")
+
+	rec := t.Append("identity", "token", pc.IdentityEndpoint, "", "", "", "", "", authLatency, "")
+	failLatency(rec, interfaceLatencyState("identity", authLatency))
+
+	entries := catalog.Entries
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Type < entries[j].Type
 	})
 
-	ret := sensu.CheckStateOK
+	probeCli := newProbeClient(tlsCfg)
+	probed := make(map[string]bool)
+	for _, entry := range entries {
+		eps := entry.Endpoints
+		sort.Slice(eps, func(i, j int) bool {
+			return eps[i].Interface < eps[j].Interface
+		})
+
+		for _, ep := range eps {
+			if !endpointInRegion(ep, eo.Region) || !slices.Contains(plugin.CatalogInterfaces, ep.Interface) {
+				continue
+			}
+
+			rootURL, err := versionRootURL(ep.URL)
+			if err != nil {
+				rec := t.Append(entry.Type, ep.Interface, ep.URL, "", "", "", "", "", "", err)
+				rec.Fail(sensu.CheckStateCritical, "invalid URL")
+				continue
+			}
+
+			if probed[rootURL] {
+				continue
+			}
+			probed[rootURL] = true
+
+			et, err := probeEndpoint(ctx, probeCli, rootURL, pc.Token())
+			if err != nil {
+				rec := t.Append(entry.Type, ep.Interface, rootURL, et.StatusCode, et.DNS, et.Connect, et.TLS, et.Response, et.Total, err)
+				rec.Fail(sensu.CheckStateCritical, "request failed")
+				continue
+			}
+
+			rec := t.Append(entry.Type, ep.Interface, rootURL, et.StatusCode, et.DNS, et.Connect, et.TLS, et.Response, et.Total, "")
+			failLatency(rec, interfaceLatencyState(ep.Interface, et.Total))
+		}
+	}
+
+	return nil
+}
 
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"ID", "Binary", "Host", "State", "Status", "Updated At", "Disable Reason"})
+func checkCertificates(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, tlsCfg *tls.Config, res *Result) error {
+	catalog, err := ServiceCatalog(pc)
+	if err != nil {
+		return err
+	}
 
-	for _, srv := range srvs {
-		t.AppendRow(table.Row{srv.ID, srv.Binary, srv.Host, srv.State, srv.Status, srv.UpdatedAt.As(), srv.DisableReason})
+	urls := []string{pc.IdentityEndpoint}
+	for _, entry := range catalog.Entries {
+		for _, ep := range entry.Endpoints {
+			if endpointInRegion(ep, eo.Region) {
+				urls = append(urls, ep.URL)
+			}
+		}
+	}
 
-		if srv.Status == "enabled" && srv.State != "up" {
-			ret = sensu.CheckStateCritical
+	hosts := make(map[string]string)
+	for _, epURL := range urls {
+		u, err := url.Parse(epURL)
+		if err != nil || u.Scheme != "https" {
+			continue
 		}
 
-		if srv.Status == "disabled" && reasonMatch(srv.DisableReason, plugin.CriticalDisabledReason) {
-			ret = sensu.CheckStateCritical
+		port := u.Port()
+		if port == "" {
+			port = "443"
 		}
+
+		hosts[net.JoinHostPort(u.Hostname(), port)] = u.Hostname()
 	}
 
-	t.Render()
+	addrs := make([]string, 0, len(hosts))
+	for addr := range hosts {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
 
-	return ret, nil
-}
+	now := time.Now()
 
-func checkBaremetal(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (int, error) {
-	cli, err := openstack.NewBareMetalV1(pc, eo)
-	if err != nil {
-		return sensu.CheckStateUnknown, err
+	t := res.AddTable("", "Address", "Subject", "Issuer", "Not After", "Days Left", "Chain", "Hostname")
+
+	errString := func(err error) string {
+		if err == nil {
+			return "ok"
+		}
+		return err.Error()
 	}
-	cli.Microversion = "1.49"
 
-	pages, err := conductors.List(cli, conductors.ListOpts{Detail: true}).AllPages(ctx)
-	if err != nil {
-		return sensu.CheckStateUnknown, err
+	for _, addr := range addrs {
+		ci, err := inspectCertificate(ctx, addr, hosts[addr], tlsCfg)
+		if err != nil {
+			rec := t.Append(addr, "", "", "", "", errString(err), "")
+			rec.Host = hosts[addr]
+			rec.Fail(sensu.CheckStateCritical, "connection failed")
+			continue
+		}
+
+		days := ci.DaysLeft(now)
+
+		rec := t.Append(addr, ci.Subject, ci.Issuer, ci.NotAfter, days, errString(ci.ChainErr), errString(ci.HostnameErr))
+		rec.Host = hosts[addr]
+
+		if ci.ChainErr != nil {
+			rec.Fail(sensu.CheckStateCritical, "chain verification failed")
+		}
+
+		if ci.HostnameErr != nil {
+			rec.Fail(sensu.CheckStateCritical, "hostname mismatch")
+		}
+
+		switch {
+		case days <= plugin.CertCriticalDays:
+			rec.Fail(sensu.CheckStateCritical, fmt.Sprintf("expires in %d days", days))
+		case days <= plugin.CertWarningDays:
+			rec.Fail(sensu.CheckStateWarning, fmt.Sprintf("expires in %d days", days))
+		}
 	}
 
-	srvs, err := conductors.ExtractConductors(pages)
+	return nil
+}
+
+func checkCanary(ctx context.Context, pc *gophercloud.ProviderClient, eo gophercloud.EndpointOpts, res *Result) error {
+	c, err := NewCanary(pc, eo, CanaryOpts{
+		Tag:        plugin.CanaryTag,
+		ImageRef:   plugin.CanaryImage,
+		FlavorRef:  plugin.CanaryFlavor,
+		NetworkID:  plugin.CanaryNetwork,
+		CIDR:       plugin.CanaryCIDR,
+		VolumeSize: plugin.CanaryVolumeSize,
+	})
 	if err != nil {
-		return sensu.CheckStateUnknown, err
+		return err
 	}
 
-	sort.Slice(srvs, func(i, j int) bool {
-		si, sj := srvs[i], srvs[j]
-		return si.Hostname < sj.Hostname
+	var leftovers int
+	err = c.step("leftovers cleanup", func() error {
+		leftovers, err = c.Cleanup(ctx)
+		return err
 	})
+	if err == nil && leftovers > 0 {
+		res.Fail(sensu.CheckStateWarning, "Deleted %d resources left by previous runs", leftovers)
+	}
 
-	ret := sensu.CheckStateOK
+	if err == nil {
+		// step errors are reported by the steps table
+		_ = c.Run(ctx)
 
-	t := table.NewWriter()
-	t.SetOutputMirror(os.Stdout)
-	t.AppendHeader(table.Row{"Host", "Conductor Group", "Drivers", "Alive", "Updated At"})
+		// cleanup must be done even if the check deadline exceeded
+		cleanupCtx, cf := context.WithTimeout(context.Background(), plugin.timeout)
+		defer cf()
 
-	for _, srv := range srvs {
-		t.AppendRow(table.Row{srv.Hostname, srv.ConductorGroup, strings.Join(srv.Drivers, " "), srv.Alive, srv.UpdatedAt})
+		_ = c.step("cleanup", func() error {
+			_, err := c.Cleanup(cleanupCtx)
+			return err
+		})
+	}
+
+	t := res.AddTable("", "Step", "Duration", "Error")
 
-		if !srv.Alive {
-			ret = sensu.CheckStateCritical
+	for _, st := range c.Steps {
+		errStr := ""
+		if st.Err != nil {
+			errStr = st.Err.Error()
 		}
-	}
 
-	t.Render()
+		rec := t.Append(st.Name, st.Duration, errStr)
+
+		state, reason := st.State(plugin.canaryWarning, plugin.canaryCritical)
+		if state != sensu.CheckStateOK {
+			rec.Fail(state, reason)
+		}
+	}
 
-	return ret, nil
+	return nil
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"testing"
 
+	corev2 "github.com/sensu/core/v2"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,3 +30,45 @@ func TestReasonMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckHost(t *testing.T) {
+	entity := corev2.FixtureEntity("cmp-1")
+	entity.Labels = map[string]string{"openstack_host": "cmp-1.example.com"}
+	event := corev2.FixtureEvent("cmp-1", "openstack")
+	event.Entity = entity
+
+	testCases := []struct {
+		name      string
+		host      string
+		label     string
+		entity    bool
+		event     *corev2.Event
+		expected  string
+		expectErr bool
+	}{
+		{"none", "", "", false, event, "", false},
+		{"option", "cmp-2", "", false, nil, "cmp-2", false},
+		{"entity", "", "", true, event, "cmp-1", false},
+		{"label", "", "openstack_host", false, event, "cmp-1.example.com", false},
+		{"no-label", "", "missing", true, event, "", true},
+		{"no-event", "", "", true, nil, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			plugin.Host, plugin.HostEntityLabel, plugin.HostFromEntity = tc.host, tc.label, tc.entity
+			defer func() {
+				plugin.Host, plugin.HostEntityLabel, plugin.HostFromEntity = "", "", false
+			}()
+
+			host, err := checkHost(tc.event)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, host)
+		})
+	}
+}
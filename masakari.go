@@ -0,0 +1,186 @@
+package main
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/pagination"
+)
+
+type MasakariSegment struct {
+	UUID           string  `json:"uuid"`
+	Name           string  `json:"name"`
+	ServiceType    string  `json:"service_type"`
+	RecoveryMethod string  `json:"recovery_method"`
+	Enabled        *bool   `json:"enabled,omitempty"`
+	CreatedAt      AnyTime `json:"created_at"`
+	UpdatedAt      AnyTime `json:"updated_at"`
+}
+
+type MasakariHost struct {
+	UUID              string  `json:"uuid"`
+	Name              string  `json:"name"`
+	Type              string  `json:"type"`
+	ControlAttributes string  `json:"control_attributes"`
+	Reserved          bool    `json:"reserved"`
+	OnMaintenance     bool    `json:"on_maintenance"`
+	FailoverSegmentID string  `json:"failover_segment_id"`
+	UpdatedAt         AnyTime `json:"updated_at"`
+}
+
+type MasakariNotification struct {
+	NotificationUUID string  `json:"notification_uuid"`
+	Type             string  `json:"type"`
+	Hostname         string  `json:"hostname"`
+	Status           string  `json:"status"`
+	GeneratedTime    AnyTime `json:"generated_time"`
+	UpdatedAt        AnyTime `json:"updated_at"`
+}
+
+type MasakariSegmentPage struct {
+	pagination.SinglePageBase
+}
+
+func (page MasakariSegmentPage) IsEmpty() (bool, error) {
+	if page.StatusCode == 204 {
+		return true, nil
+	}
+
+	segments, err := ExtractMasakariSegments(page)
+	return len(segments) == 0, err
+}
+
+func ExtractMasakariSegments(r pagination.Page) ([]MasakariSegment, error) {
+	var s struct {
+		Segments []MasakariSegment `json:"segments"`
+	}
+	err := (r.(MasakariSegmentPage)).ExtractInto(&s)
+	return s.Segments, err
+}
+
+func MasakariSegmentList(client *gophercloud.ServiceClient) pagination.Pager {
+	url := client.ServiceURL("segments")
+
+	return pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
+		return MasakariSegmentPage{pagination.SinglePageBase(r)}
+	})
+}
+
+type MasakariHostPage struct {
+	pagination.SinglePageBase
+}
+
+func (page MasakariHostPage) IsEmpty() (bool, error) {
+	if page.StatusCode == 204 {
+		return true, nil
+	}
+
+	hosts, err := ExtractMasakariHosts(page)
+	return len(hosts) == 0, err
+}
+
+func ExtractMasakariHosts(r pagination.Page) ([]MasakariHost, error) {
+	var s struct {
+		Hosts []MasakariHost `json:"hosts"`
+	}
+	err := (r.(MasakariHostPage)).ExtractInto(&s)
+	return s.Hosts, err
+}
+
+func MasakariHostList(client *gophercloud.ServiceClient, segmentID string) pagination.Pager {
+	url := client.ServiceURL("segments", segmentID, "hosts")
+
+	return pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
+		return MasakariHostPage{pagination.SinglePageBase(r)}
+	})
+}
+
+type MasakariNotificationPage struct {
+	pagination.SinglePageBase
+}
+
+func (page MasakariNotificationPage) IsEmpty() (bool, error) {
+	if page.StatusCode == 204 {
+		return true, nil
+	}
+
+	notifications, err := ExtractMasakariNotifications(page)
+	return len(notifications) == 0, err
+}
+
+func ExtractMasakariNotifications(r pagination.Page) ([]MasakariNotification, error) {
+	var s struct {
+		Notifications []MasakariNotification `json:"notifications"`
+	}
+	err := (r.(MasakariNotificationPage)).ExtractInto(&s)
+	return s.Notifications, err
+}
+
+func MasakariNotificationList(client *gophercloud.ServiceClient, generatedSince time.Time) pagination.Pager {
+	q := url.Values{}
+	q.Set("generated-since", generatedSince.UTC().Format(time.RFC3339))
+	url := client.ServiceURL("notifications") + "?" + q.Encode()
+
+	return pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
+		return MasakariNotificationPage{pagination.SinglePageBase(r)}
+	})
+}
+
+// MasakariHostNotificationList returns the last host failure notification of the segment host, whenever it was generated.
+func MasakariHostNotificationList(client *gophercloud.ServiceClient, hostUUID string) pagination.Pager {
+	q := url.Values{}
+	q.Set("source_host_uuid", hostUUID)
+	q.Set("type", "COMPUTE_HOST")
+	q.Set("sort_key", "generated_time")
+	q.Set("sort_dir", "desc")
+	q.Set("limit", "1")
+	url := client.ServiceURL("notifications") + "?" + q.Encode()
+
+	return pagination.NewPager(client, url, func(r pagination.PageResult) pagination.Page {
+		return MasakariNotificationPage{pagination.SinglePageBase(r)}
+	})
+}
+
+// masakariRecoveredHosts returns hosts with host failure notifications,
+// as Masakari puts them on maintenance during recovery and leaves them there.
+func masakariRecoveredHosts(notifs []MasakariNotification) map[string]bool {
+	ret := make(map[string]bool)
+	for _, n := range notifs {
+		if n.Type == "COMPUTE_HOST" {
+			ret[n.Hostname] = true
+		}
+	}
+	return ret
+}
+
+// masakariHostProblems returns problems of the segment host, planned maintenance is not a problem.
+func masakariHostProblems(h MasakariHost, segmentEnabled bool, recovered map[string]bool) []string {
+	ret := make([]string, 0)
+	if !segmentEnabled {
+		ret = append(ret, "segment disabled")
+	}
+	if h.OnMaintenance && recovered[h.Name] {
+		ret = append(ret, "host on maintenance after recovery")
+	}
+	return ret
+}
+
+// masakariNotificationProblem returns description of unfinished notification or empty string.
+func masakariNotificationProblem(n MasakariNotification, deadline time.Time) string {
+	switch n.Status {
+	case "error":
+	case "new", "running":
+		if n.GeneratedTime.As().After(deadline) {
+			return ""
+		}
+	default:
+		return ""
+	}
+
+	return "notification " + n.Status
+}
+
+func NewInstanceHAV1(client *gophercloud.ProviderClient, eo gophercloud.EndpointOpts) (*gophercloud.ServiceClient, error) {
+	return NewServiceClient(client, eo, "instance-ha")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMasakariList(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /segments/s1/hosts", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"hosts": [{"uuid": "h1", "name": "cmp-1", "type": "COMPUTE", "reserved": false,
+			"on_maintenance": true, "failover_segment_id": "s1", "updated_at": "2024-05-01T10:00:00.000000"}]}`)
+	})
+	mux.HandleFunc("GET /notifications", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2024-05-01T00:00:00Z", r.URL.Query().Get("generated-since"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"notifications": [{"notification_uuid": "n1", "type": "COMPUTE_HOST", "hostname": "cmp-1",
+			"status": "finished", "generated_time": "2024-05-01T09:00:00.000000", "updated_at": null}]}`)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cli := &gophercloud.ServiceClient{ProviderClient: &gophercloud.ProviderClient{TokenID: "token"}, Endpoint: srv.URL + "/"}
+	ctx := context.Background()
+
+	pages, err := MasakariHostList(cli, "s1").AllPages(ctx)
+	require.NoError(t, err)
+
+	hosts, err := ExtractMasakariHosts(pages)
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, "cmp-1", hosts[0].Name)
+	assert.True(t, hosts[0].OnMaintenance)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), hosts[0].UpdatedAt.As())
+
+	pages, err = MasakariNotificationList(cli, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)).AllPages(ctx)
+	require.NoError(t, err)
+
+	notifs, err := ExtractMasakariNotifications(pages)
+	require.NoError(t, err)
+	require.Len(t, notifs, 1)
+	assert.Equal(t, "COMPUTE_HOST", notifs[0].Type)
+	assert.Equal(t, time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC), notifs[0].GeneratedTime.As())
+}
+
+func TestMasakariHostNotificationList(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /notifications", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		assert.Equal(t, "h1", q.Get("source_host_uuid"))
+		assert.Equal(t, "COMPUTE_HOST", q.Get("type"))
+		assert.Equal(t, "1", q.Get("limit"))
+		assert.Empty(t, q.Get("generated-since"))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"notifications": [{"notification_uuid": "n1", "type": "COMPUTE_HOST", "hostname": "cmp-1",
+			"status": "finished", "generated_time": "2023-01-01T09:00:00.000000", "updated_at": null}]}`)
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	cli := &gophercloud.ServiceClient{ProviderClient: &gophercloud.ProviderClient{TokenID: "token"}, Endpoint: srv.URL + "/"}
+
+	pages, err := MasakariHostNotificationList(cli, "h1").AllPages(context.Background())
+	require.NoError(t, err)
+
+	last, err := ExtractMasakariNotifications(pages)
+	require.NoError(t, err)
+
+	// recovery is far older than the time window, the host is still left on maintenance
+	recovered := masakariRecoveredHosts(nil)
+	maps.Copy(recovered, masakariRecoveredHosts(last))
+	assert.Equal(t, []string{"host on maintenance after recovery"},
+		masakariHostProblems(MasakariHost{UUID: "h1", Name: "cmp-1", OnMaintenance: true}, true, recovered))
+}
+
+func TestMasakariProblems(t *testing.T) {
+	now := time.Now()
+	deadline := now.Add(-10 * time.Minute)
+	old := AnyTime(now.Add(-time.Hour))
+	recent := AnyTime(now)
+
+	notifs := []MasakariNotification{
+		{Type: "COMPUTE_HOST", Hostname: "cmp-1", Status: "finished"},
+		{Type: "VM", Hostname: "cmp-2", Status: "finished"},
+	}
+	recovered := masakariRecoveredHosts(notifs)
+	assert.Equal(t, map[string]bool{"cmp-1": true}, recovered)
+
+	t.Run("host", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Empty(masakariHostProblems(MasakariHost{Name: "cmp-1"}, true, recovered))
+		assert.Equal([]string{"host on maintenance after recovery"}, masakariHostProblems(MasakariHost{Name: "cmp-1", OnMaintenance: true}, true, recovered))
+		assert.Empty(masakariHostProblems(MasakariHost{Name: "cmp-2", OnMaintenance: true}, true, recovered), "planned maintenance")
+		assert.Equal([]string{"segment disabled"}, masakariHostProblems(MasakariHost{Name: "cmp-3"}, false, recovered))
+	})
+
+	t.Run("notification", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Equal("", masakariNotificationProblem(MasakariNotification{Status: "finished", GeneratedTime: old}, deadline))
+		assert.Equal("notification error", masakariNotificationProblem(MasakariNotification{Status: "error", GeneratedTime: recent}, deadline))
+		assert.Equal("", masakariNotificationProblem(MasakariNotification{Status: "running", GeneratedTime: recent}, deadline))
+		assert.Equal("notification running", masakariNotificationProblem(MasakariNotification{Status: "running", GeneratedTime: old}, deadline))
+		assert.Equal("notification new", masakariNotificationProblem(MasakariNotification{Status: "new", GeneratedTime: old}, deadline))
+	})
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu/metric"
+)
+
+// Metric output formats supported by Sensu output_metric_format.
+const (
+	NagiosPerfdata    = "nagios_perfdata"
+	GraphitePlaintext = "graphite_plaintext"
+	InfluxDBLine      = "influxdb_line"
+	OpenTSDBLine      = "opentsdb_line"
+	PrometheusText    = "prometheus_text"
+)
+
+var metricFormats = []string{NagiosPerfdata, GraphitePlaintext, InfluxDBLine, OpenTSDBLine, PrometheusText}
+
+var metricUnsafeChars = regexp.MustCompile(`[^a-zA-Z0-9_.\-/]`)
+
+type serviceCountKey struct {
+	Binary string
+	Zone   string
+	Host   string
+}
+
+// resultMetrics returns the check state, up/down/disabled counts and heartbeat ages of the service records.
+//
+// Tags order is kept for the formats without tags support, where tag values become metric path.
+func resultMetrics(res *Result, now time.Time) metric.Points {
+	ts := now.Unix()
+	points := metric.Points{
+		newMetricPoint("openstack_check_state", float64(res.State), ts, "service", res.Service),
+	}
+
+	counts := make(map[serviceCountKey]map[string]int)
+	ages := make(map[serviceCountKey]float64)
+
+	for _, t := range res.Tables {
+		for _, rec := range t.Records {
+			if rec.Status == nil {
+				continue
+			}
+
+			key := serviceCountKey{rec.Binary, rec.Zone, rec.Host}
+			if counts[key] == nil {
+				counts[key] = map[string]int{"up": 0, "down": 0, "disabled": 0}
+			}
+
+			switch {
+			case !rec.Status.Enabled:
+				counts[key]["disabled"]++
+			case rec.Status.Up:
+				counts[key]["up"]++
+			default:
+				counts[key]["down"]++
+			}
+
+			if !rec.Status.Heartbeat.IsZero() {
+				ages[key] = max(ages[key], now.Sub(rec.Status.Heartbeat).Seconds())
+			}
+		}
+	}
+
+	keys := make([]serviceCountKey, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		ki, kj := keys[i], keys[j]
+		return ki.Binary < kj.Binary || (ki.Binary == kj.Binary && (ki.Zone < kj.Zone || (ki.Zone == kj.Zone && ki.Host < kj.Host)))
+	})
+
+	for _, key := range keys {
+		for _, status := range []string{"up", "down", "disabled"} {
+			points = append(points, newMetricPoint("openstack_services", float64(counts[key][status]), ts,
+				"service", res.Service, "binary", key.Binary, "zone", key.Zone, "host", key.Host, "status", status))
+		}
+
+		age, ok := ages[key]
+		if ok {
+			points = append(points, newMetricPoint("openstack_service_heartbeat_age_seconds", age, ts,
+				"service", res.Service, "binary", key.Binary, "zone", key.Zone, "host", key.Host))
+		}
+	}
+
+	return points
+}
+
+func newMetricPoint(name string, value float64, ts int64, tags ...string) *corev2.MetricPoint {
+	p := &corev2.MetricPoint{Name: name, Value: value, Timestamp: ts}
+	for i := 0; i+1 < len(tags); i += 2 {
+		p.Tags = append(p.Tags, &corev2.MetricTag{Name: tags[i], Value: tags[i+1]})
+	}
+	return p
+}
+
+// metricPath joins the name and tag values for the formats without tags support.
+func metricPath(p *corev2.MetricPoint) string {
+	parts := []string{strings.Replace(p.Name, "_", ".", 1)}
+	for _, tag := range p.Tags {
+		v := tag.Value
+		if v == "" {
+			v = "none"
+		}
+		parts = append(parts, metricUnsafeChars.ReplaceAllString(strings.ReplaceAll(v, ".", "_"), "_"))
+	}
+	return strings.Join(parts, ".")
+}
+
+func formatMetricValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// writeMetrics writes the points in one of the Sensu metric formats.
+//
+// Nagios perfdata is prefixed by the summary line, as the format requires.
+func writeMetrics(w io.Writer, format string, res *Result, points metric.Points) error {
+	switch format {
+	case NagiosPerfdata:
+		perf := make([]string, 0, len(points))
+		for _, p := range points {
+			perf = append(perf, metricPath(p)+"="+formatMetricValue(p.Value))
+		}
+
+		_, err := fmt.Fprintf(w, "%s | %s\n", res.Summary, strings.Join(perf, " "))
+		return err
+
+	case GraphitePlaintext:
+		for _, p := range points {
+			_, err := fmt.Fprintf(w, "%s %s %d\n", metricPath(p), formatMetricValue(p.Value), p.Timestamp)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case InfluxDBLine:
+		escaper := strings.NewReplacer(" ", `\ `, ",", `\,`, "=", `\=`)
+		for _, p := range points {
+			measurement := escaper.Replace(p.Name)
+			for _, tag := range p.Tags {
+				if tag.Value != "" {
+					measurement += "," + tag.Name + "=" + escaper.Replace(tag.Value)
+				}
+			}
+
+			_, err := fmt.Fprintf(w, "%s value=%s %d\n", measurement, formatMetricValue(p.Value), p.Timestamp)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case OpenTSDBLine:
+		for _, p := range points {
+			tags := make([]string, 0, len(p.Tags))
+			for _, tag := range p.Tags {
+				if tag.Value != "" {
+					tags = append(tags, tag.Name+"="+metricUnsafeChars.ReplaceAllString(tag.Value, "_"))
+				}
+			}
+
+			_, err := fmt.Fprintf(w, "put %s %d %s %s\n", p.Name, p.Timestamp, formatMetricValue(p.Value), strings.Join(tags, " "))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case PrometheusText:
+		gauges := make(metric.Points, 0, len(points))
+		for _, p := range points {
+			tags := append(slices.Clip(p.Tags), &corev2.MetricTag{Name: "prom_type", Value: "gauge"})
+			gauges = append(gauges, &corev2.MetricPoint{Name: p.Name, Value: p.Value, Timestamp: p.Timestamp, Tags: tags})
+		}
+		return gauges.ToProm(w)
+
+	default:
+		return fmt.Errorf("unsupported metric format: %s", format)
+	}
+}
+
+// serviceRecordMetrics returns up and enabled gauges of every service record.
+// The record ID is a label, as heat-engine workers share binary and host.
+func serviceRecordMetrics(res *Result, now time.Time) metric.Points {
+	ts := now.Unix()
+	points := make(metric.Points, 0)
+
+	boolValue := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	for _, t := range res.Tables {
+		for _, rec := range t.Records {
+			if rec.Status == nil {
+				continue
+			}
+
+			tags := []string{"service", res.Service, "binary", rec.Binary, "zone", rec.Zone, "host", rec.Host, "id", rec.ID}
+			points = append(points,
+				newMetricPoint("openstack_service_up", boolValue(rec.Status.Up), ts, tags...),
+				newMetricPoint("openstack_service_enabled", boolValue(rec.Status.Enabled), ts, tags...),
+			)
+		}
+	}
+
+	return points
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetricsResult(now time.Time) *Result {
+	res := NewResult("compute")
+	t := res.AddTable("", "Binary", "Host")
+
+	for _, s := range []struct {
+		host    string
+		enabled bool
+		up      bool
+	}{
+		{"cmp-1", true, true},
+		{"cmp-2", true, false},
+		{"cmp-3", false, false},
+	} {
+		rec := t.Append("nova-compute", s.host)
+		rec.Binary, rec.Zone, rec.Host = "nova-compute", "nova", s.host
+		evaluateService(rec, s.enabled, s.up, now.Add(-90*time.Second), nil)
+	}
+
+	res.Evaluate()
+	return res
+}
+
+func TestResultMetrics(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	points := resultMetrics(testMetricsResult(now), now)
+
+	// state + 3 hosts * (3 counts + heartbeat age)
+	require.Len(t, points, 13)
+
+	assert.Equal(t, "openstack_check_state", points[0].Name)
+	assert.Equal(t, float64(sensu.CheckStateCritical), points[0].Value)
+
+	values := make(map[string]float64)
+	for _, p := range points {
+		assert.Equal(t, now.Unix(), p.Timestamp)
+		values[metricPath(p)] = p.Value
+	}
+
+	assert.Equal(t, 1.0, values["openstack.services.compute.nova-compute.nova.cmp-1.up"])
+	assert.Equal(t, 1.0, values["openstack.services.compute.nova-compute.nova.cmp-2.down"])
+	assert.Equal(t, 0.0, values["openstack.services.compute.nova-compute.nova.cmp-2.up"])
+	assert.Equal(t, 1.0, values["openstack.services.compute.nova-compute.nova.cmp-3.disabled"])
+	assert.Equal(t, 90.0, values["openstack.service_heartbeat_age_seconds.compute.nova-compute.nova.cmp-1"])
+}
+
+func TestMetricPath(t *testing.T) {
+	p := newMetricPoint("openstack_services", 1, 0, "host", "cmp-1.example.com", "zone", "", "binary", "nova compute")
+	assert.Equal(t, "openstack.services.cmp-1_example_com.none.nova_compute", metricPath(p))
+}
+
+func TestWriteMetrics(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	res := testMetricsResult(now)
+
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{NagiosPerfdata, "CRITICAL compute: 1/3 down (nova-compute@cmp-2); 1 disabled | openstack.check_state.compute=2 openstack.services.compute.nova-compute.nova.cmp-1.up=1 "},
+		{GraphitePlaintext, "openstack.check_state.compute 2 1700000000\nopenstack.services.compute.nova-compute.nova.cmp-1.up 1 1700000000\n"},
+		{InfluxDBLine, "openstack_check_state,service=compute value=2 1700000000\nopenstack_services,service=compute,binary=nova-compute,zone=nova,host=cmp-1,status=up value=1 1700000000\n"},
+		{OpenTSDBLine, "put openstack_check_state 1700000000 2 service=compute\nput openstack_services 1700000000 1 service=compute binary=nova-compute zone=nova host=cmp-1 status=up\n"},
+		{PrometheusText, "# TYPE openstack_services gauge\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := writeMetrics(&buf, tc.format, res, resultMetrics(res, now))
+			require.NoError(t, err)
+			assert.Contains(t, buf.String(), tc.expected)
+		})
+	}
+
+	err := writeMetrics(&bytes.Buffer{}, "carbon", res, nil)
+	assert.Error(t, err)
+}
@@ -2,10 +2,22 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/gophercloud/gophercloud/v2"
 	netagents "github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/agents"
+	ipavail "github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/networkipavailabilities"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/portsbinding"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/ports"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/subnets"
 	"github.com/gophercloud/gophercloud/v2/pagination"
 )
 
@@ -90,3 +102,297 @@ func ExtractNeutronAgents(r pagination.Page) ([]NeutronAgent, error) {
 	err := (r.(NeutronAgentPage)).ExtractInto(&s)
 	return s.Agents, err
 }
+
+// -*- DHCP agent redundancy -*-
+
+// dhcpNetworkIDs returns set of networks having at least one DHCP-enabled subnet.
+func dhcpNetworkIDs(subs []subnets.Subnet) map[string]struct{} {
+	ids := make(map[string]struct{})
+	for _, sub := range subs {
+		if sub.EnableDHCP {
+			ids[sub.NetworkID] = struct{}{}
+		}
+	}
+	return ids
+}
+
+// dhcpLackingNetworks returns networks with DHCP-enabled subnets which hosted by less than minAgents alive agents.
+func dhcpLackingNetworks(subs []subnets.Subnet, nets []networks.Network, hosting map[string][]string, minAgents int) []networks.Network {
+	ids := dhcpNetworkIDs(subs)
+
+	ret := make([]networks.Network, 0)
+	for _, n := range nets {
+		if _, ok := ids[n.ID]; !ok {
+			continue
+		}
+
+		if len(hosting[n.ID]) < minAgents {
+			ret = append(ret, n)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		ni, nj := ret[i], ret[j]
+		return ni.Name < nj.Name || (ni.Name == nj.Name && ni.ID < nj.ID)
+	})
+
+	return ret
+}
+
+// -*- ML2/OVN agents -*-
+
+const (
+	OVNControllerAgent        = "OVN Controller agent"
+	OVNControllerGatewayAgent = "OVN Controller Gateway agent"
+	OVNMetadataAgent          = "OVN Metadata agent"
+)
+
+type OVNAgentsState struct {
+	Controllers   int
+	Gateways      int
+	AliveGateways []string
+	NoMetadata    []string
+}
+
+// ovnAgentsState evaluates OVN chassis: alive gateways and controller chassis without alive metadata agent.
+func ovnAgentsState(agents []NeutronAgent) OVNAgentsState {
+	var st OVNAgentsState
+
+	metadata := make(map[string]bool)
+	for _, ag := range agents {
+		if ag.AgentType == OVNMetadataAgent && ag.AdminStateUp && ag.Alive {
+			metadata[ag.Host] = true
+		}
+	}
+
+	for _, ag := range agents {
+		switch ag.AgentType {
+		case OVNControllerGatewayAgent:
+			st.Gateways++
+			if ag.AdminStateUp && ag.Alive {
+				st.AliveGateways = append(st.AliveGateways, ag.Host)
+			}
+
+		case OVNControllerAgent:
+			st.Controllers++
+			if !metadata[ag.Host] {
+				st.NoMetadata = append(st.NoMetadata, ag.Host)
+			}
+		}
+	}
+
+	sort.Strings(st.AliveGateways)
+	sort.Strings(st.NoMetadata)
+
+	return st
+}
+
+// -*- agent configuration drift -*-
+
+// neutronDriftKeys are agent configuration keys which expected to be the same on all agents of the same binary.
+//
+// Interface mappings name host NICs, which are often different, so they are not compared.
+var neutronDriftKeys = []string{"bridge_mappings", "tunnel_types", "bridge-mappings"}
+
+// agentBinaryRe is the format of agent binary names, the binaries of a cloud are not known before the agent list.
+var agentBinaryRe = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// validateDriftBaseline checks that baseline keys are "<binary>/<key>" of the compared keys.
+func validateDriftBaseline(baseline map[string]string) error {
+	for key := range baseline {
+		binary, cfgKey, ok := strings.Cut(key, "/")
+		if !ok || binary == "" || cfgKey == "" {
+			return fmt.Errorf("drift baseline key must be <binary>/<key>: %s", key)
+		}
+		if !agentBinaryRe.MatchString(binary) {
+			return fmt.Errorf("drift baseline key has invalid agent binary: %s", key)
+		}
+		if !slices.Contains(neutronDriftKeys, cfgKey) {
+			return fmt.Errorf("drift baseline key %s is not one of: %s", key, strings.Join(neutronDriftKeys, ", "))
+		}
+	}
+	return nil
+}
+
+type AgentDrift struct {
+	AgentType string
+	Host      string
+	Key       string
+	Value     string
+	Expected  string
+}
+
+// normalizeAgentConfig converts configuration value to comparable string.
+//
+// Maps are rendered as sorted "key:value" pairs, lists and comma or space separated strings are sorted.
+func normalizeAgentConfig(v any) string {
+	var items []string
+
+	switch val := v.(type) {
+	case nil:
+	case map[string]any:
+		for k, iv := range val {
+			items = append(items, fmt.Sprintf("%s:%v", k, iv))
+		}
+	case []any:
+		for _, iv := range val {
+			items = append(items, fmt.Sprint(iv))
+		}
+	case string:
+		items = strings.FieldsFunc(val, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+	default:
+		items = append(items, fmt.Sprint(val))
+	}
+
+	sort.Strings(items)
+	return strings.Join(items, " ")
+}
+
+// missingAgentConfig is the drift value of the key absent in the agent configuration.
+const missingAgentConfig = "(missing)"
+
+func agentConfigValue(ag NeutronAgent, key string) string {
+	v, ok := ag.Configurations[key]
+	if !ok {
+		return missingAgentConfig
+	}
+	return normalizeAgentConfig(v)
+}
+
+// agentConfigDrift returns agents which configuration differs from the baseline or the majority of the same agent binary.
+//
+// Baseline keys are "<binary>/<key>", e.g. neutron-openvswitch-agent/bridge_mappings.
+// Keys reported by any agent of the binary or set in the baseline are compared on all of them,
+// so the missing key is a drift too.
+func agentConfigDrift(agents []NeutronAgent, baseline map[string]string) []AgentDrift {
+	type groupKey struct {
+		binary string
+		key    string
+	}
+
+	byBinary := make(map[string][]NeutronAgent)
+	for _, ag := range agents {
+		byBinary[ag.Binary] = append(byBinary[ag.Binary], ag)
+	}
+
+	groups := make(map[groupKey][]NeutronAgent)
+	for key := range baseline {
+		binary, cfgKey, _ := strings.Cut(key, "/")
+		if bAgents, ok := byBinary[binary]; ok {
+			groups[groupKey{binary, cfgKey}] = bAgents
+		}
+	}
+
+	for binary, bAgents := range byBinary {
+		for _, key := range neutronDriftKeys {
+			for _, ag := range bAgents {
+				if _, ok := ag.Configurations[key]; ok {
+					groups[groupKey{binary, key}] = bAgents
+					break
+				}
+			}
+		}
+	}
+
+	ret := make([]AgentDrift, 0)
+	for gk, gAgents := range groups {
+		expected, ok := baseline[gk.binary+"/"+gk.key]
+		if ok {
+			expected = normalizeAgentConfig(expected)
+		} else {
+			counts := make(map[string]int)
+			for _, ag := range gAgents {
+				counts[agentConfigValue(ag, gk.key)]++
+			}
+
+			best := -1
+			for v, c := range counts {
+				if c > best || (c == best && v < expected) {
+					expected, best = v, c
+				}
+			}
+		}
+
+		for _, ag := range gAgents {
+			v := agentConfigValue(ag, gk.key)
+			if v != expected {
+				ret = append(ret, AgentDrift{
+					AgentType: ag.AgentType,
+					Host:      ag.Host,
+					Key:       gk.key,
+					Value:     v,
+					Expected:  expected,
+				})
+			}
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		di, dj := ret[i], ret[j]
+		if di.AgentType != dj.AgentType {
+			return di.AgentType < dj.AgentType
+		}
+		if di.Host != dj.Host {
+			return di.Host < dj.Host
+		}
+		return di.Key < dj.Key
+	})
+
+	return ret
+}
+
+// -*- ports with binding details -*-
+
+type NeutronPort struct {
+	ports.Port
+	portsbinding.PortsBindingExt
+}
+
+// UnmarshalJSON decodes both parts, ports.Port.UnmarshalJSON would hide binding fields otherwise.
+func (r *NeutronPort) UnmarshalJSON(b []byte) error {
+	err := json.Unmarshal(b, &r.Port)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, &r.PortsBindingExt)
+}
+
+func ExtractNeutronPorts(r pagination.Page) ([]NeutronPort, error) {
+	var s []NeutronPort
+	err := ports.ExtractPortsInto(r, &s)
+	return s, err
+}
+
+// portBindingFailed reports if port is owned by some device but not bound.
+func portBindingFailed(p NeutronPort, ignoreOwners []string) bool {
+	if p.DeviceOwner == "" || slices.Contains(ignoreOwners, p.DeviceOwner) {
+		return false
+	}
+
+	return p.VIFType == "binding_failed" || p.VIFType == "unbound"
+}
+
+// -*- IP availability -*-
+
+// subnetIPUsage returns percent of used IPs in the subnet.
+func subnetIPUsage(sub ipavail.SubnetIPAvailability) (float64, error) {
+	total, ok := new(big.Float).SetString(sub.TotalIPs)
+	if !ok {
+		return 0, fmt.Errorf("invalid total IPs: %q", sub.TotalIPs)
+	}
+
+	used, ok := new(big.Float).SetString(sub.UsedIPs)
+	if !ok {
+		return 0, fmt.Errorf("invalid used IPs: %q", sub.UsedIPs)
+	}
+
+	if total.Sign() == 0 {
+		return 0, nil
+	}
+
+	pct, _ := new(big.Float).Quo(new(big.Float).Mul(used, big.NewFloat(100)), total).Float64()
+	return pct, nil
+}
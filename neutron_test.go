@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	ipavail "github.com/gophercloud/gophercloud/v2/openstack/networking/v2/extensions/networkipavailabilities"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/v2/openstack/networking/v2/subnets"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDHCPLackingNetworks(t *testing.T) {
+	subs := []subnets.Subnet{
+		{ID: "s1", NetworkID: "n1", EnableDHCP: true},
+		{ID: "s2", NetworkID: "n2", EnableDHCP: true},
+		{ID: "s3", NetworkID: "n3", EnableDHCP: false},
+		{ID: "s4", NetworkID: "n4", EnableDHCP: true},
+	}
+	nets := []networks.Network{
+		{ID: "n1", Name: "net1"},
+		{ID: "n2", Name: "net2"},
+		{ID: "n3", Name: "net3"},
+		{ID: "n4", Name: "net0"},
+	}
+	hosting := map[string][]string{
+		"n1": {"ctl1", "ctl2"},
+		"n2": {"ctl1"},
+	}
+
+	testCases := []struct {
+		name      string
+		minAgents int
+		expected  []string
+	}{
+		{"one", 1, []string{"n4"}},
+		{"two", 2, []string{"n4", "n2"}},
+		{"three", 3, []string{"n4", "n1", "n2"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obtained := dhcpLackingNetworks(subs, nets, hosting, tc.minAgents)
+
+			ids := make([]string, 0, len(obtained))
+			for _, n := range obtained {
+				ids = append(ids, n.ID)
+			}
+			assert.Equal(t, tc.expected, ids)
+		})
+	}
+}
+
+func TestOVNAgentsState(t *testing.T) {
+	agent := func(typ, host string, alive bool) NeutronAgent {
+		var ag NeutronAgent
+		ag.AgentType = typ
+		ag.Host = host
+		ag.Alive = alive
+		ag.AdminStateUp = true
+		return ag
+	}
+
+	agents := []NeutronAgent{
+		agent(OVNControllerGatewayAgent, "net1", true),
+		agent(OVNControllerGatewayAgent, "net2", false),
+		agent(OVNControllerAgent, "cmp1", true),
+		agent(OVNControllerAgent, "cmp2", true),
+		agent(OVNControllerAgent, "cmp3", true),
+		agent(OVNMetadataAgent, "cmp1", true),
+		agent(OVNMetadataAgent, "cmp2", false),
+	}
+
+	st := ovnAgentsState(agents)
+
+	assert := assert.New(t)
+	assert.Equal(3, st.Controllers)
+	assert.Equal(2, st.Gateways)
+	assert.Equal([]string{"net1"}, st.AliveGateways)
+	assert.Equal([]string{"cmp2", "cmp3"}, st.NoMetadata)
+
+	plugin.OVNCheckMetadata = true
+	defer func() {
+		plugin.OVNCheckMetadata = false
+	}()
+
+	res := NewResult("network")
+	checkOVNAgents(agents, res)
+
+	hostRes := res.FilterHost("cmp2.example.com")
+	require.Len(t, hostRes.Tables, 1)
+	require.Len(t, hostRes.Tables[0].Records, 1)
+	assert.Equal("cmp2", hostRes.Tables[0].Records[0].Host)
+	assert.Equal(sensu.CheckStateCritical, hostRes.Evaluate())
+
+	assert.Equal(sensu.CheckStateOK, res.FilterHost("cmp1").Evaluate())
+}
+
+func TestAgentConfigDrift(t *testing.T) {
+	agent := func(host string, cfg map[string]any) NeutronAgent {
+		var ag NeutronAgent
+		ag.AgentType = "Open vSwitch agent"
+		ag.Binary = "neutron-openvswitch-agent"
+		ag.Host = host
+		ag.Configurations = cfg
+		return ag
+	}
+
+	agents := []NeutronAgent{
+		agent("cmp1", map[string]any{"bridge_mappings": map[string]any{"physnet1": "br-ex", "physnet2": "br-vlan"}, "tunnel_types": []any{"vxlan"}}),
+		agent("cmp2", map[string]any{"bridge_mappings": map[string]any{"physnet2": "br-vlan", "physnet1": "br-ex"}, "tunnel_types": []any{"vxlan"}}),
+		agent("cmp3", map[string]any{"bridge_mappings": map[string]any{"physnet1": "br-ex"}, "tunnel_types": []any{"vxlan"}}),
+	}
+
+	t.Run("majority", func(t *testing.T) {
+		obtained := agentConfigDrift(agents, nil)
+		assert.Equal(t, []AgentDrift{
+			{"Open vSwitch agent", "cmp3", "bridge_mappings", "physnet1:br-ex", "physnet1:br-ex physnet2:br-vlan"},
+		}, obtained)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		obtained := agentConfigDrift(append(agents, agent("cmp4", map[string]any{"tunnel_types": []any{"vxlan"}})), nil)
+		assert.Equal(t, []AgentDrift{
+			{"Open vSwitch agent", "cmp3", "bridge_mappings", "physnet1:br-ex", "physnet1:br-ex physnet2:br-vlan"},
+			{"Open vSwitch agent", "cmp4", "bridge_mappings", missingAgentConfig, "physnet1:br-ex physnet2:br-vlan"},
+		}, obtained)
+	})
+
+	t.Run("baseline", func(t *testing.T) {
+		obtained := agentConfigDrift(agents, map[string]string{"neutron-openvswitch-agent/tunnel_types": "vxlan gre"})
+		assert.Len(t, obtained, 4)
+		assert.Equal(t, "gre vxlan", obtained[0].Expected)
+
+		dhcp := NeutronAgent{}
+		dhcp.AgentType, dhcp.Binary, dhcp.Host = "DHCP agent", "neutron-dhcp-agent", "net1"
+		dhcp.Configurations = map[string]any{"tunnel_types": []any{"vxlan"}}
+
+		obtained = agentConfigDrift([]NeutronAgent{dhcp}, map[string]string{"neutron-openvswitch-agent/tunnel_types": "gre"})
+		assert.Empty(t, obtained, "baseline of another binary")
+	})
+
+	t.Run("baseline-missing", func(t *testing.T) {
+		obtained := agentConfigDrift(agents, map[string]string{"neutron-openvswitch-agent/bridge-mappings": "physnet1:br-ex"})
+		assert.Len(t, obtained, 4, "cmp3 majority drift and key missing on all agents")
+		assert.Equal(t, AgentDrift{"Open vSwitch agent", "cmp1", "bridge-mappings", missingAgentConfig, "physnet1:br-ex"}, obtained[0])
+	})
+}
+
+func TestValidateDriftBaseline(t *testing.T) {
+	testCases := []struct {
+		key string
+		ok  bool
+	}{
+		{"neutron-openvswitch-agent/bridge_mappings", true},
+		{"ovn-controller/bridge-mappings", true},
+		{"neutron-linuxbridge-agent/tunnel_types", true},
+		{"bridge_mappings", false},
+		{"/bridge_mappings", false},
+		{"neutron-openvswitch-agent/", false},
+		{"Open vSwitch agent/bridge_mappings", false},
+		{"neutron-linuxbridge-agent/interface_mappings", false},
+		{"neutron-openvswitch-agent/bridge_mapping", false},
+	}
+
+	for _, tc := range testCases {
+		err := validateDriftBaseline(map[string]string{tc.key: "x"})
+		if tc.ok {
+			assert.NoError(t, err, tc.key)
+		} else {
+			assert.Error(t, err, tc.key)
+		}
+	}
+}
+
+func TestNeutronPortBinding(t *testing.T) {
+	ignore := []string{"network:floatingip"}
+
+	testCases := []struct {
+		name     string
+		js       string
+		expected bool
+	}{
+		{"bound", `{"id": "p1", "device_owner": "compute:nova", "binding:vif_type": "ovs", "binding:host_id": "cmp1", "updated_at": "2023-03-16T18:35:47Z"}`, false},
+		{"failed", `{"id": "p2", "device_owner": "compute:nova", "binding:vif_type": "binding_failed", "binding:host_id": "cmp1"}`, true},
+		{"unbound", `{"id": "p3", "device_owner": "network:dhcp", "binding:vif_type": "unbound"}`, true},
+		{"no-owner", `{"id": "p4", "device_owner": "", "binding:vif_type": "unbound"}`, false},
+		{"ignored", `{"id": "p5", "device_owner": "network:floatingip", "binding:vif_type": "unbound"}`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var p NeutronPort
+			err := json.Unmarshal([]byte(tc.js), &p)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, p.ID)
+			assert.NotEmpty(t, p.VIFType)
+			assert.Equal(t, tc.expected, portBindingFailed(p, ignore))
+		})
+	}
+}
+
+func TestSubnetIPUsage(t *testing.T) {
+	testCases := []struct {
+		name     string
+		total    string
+		used     string
+		expected float64
+	}{
+		{"empty", "0", "0", 0},
+		{"half", "254", "127", 50},
+		{"v6", "18446744073709551616", "4611686018427387904", 25},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obtained, err := subnetIPUsage(ipavail.SubnetIPAvailability{TotalIPs: tc.total, UsedIPs: tc.used})
+			assert.NoError(t, err)
+			assert.InDelta(t, tc.expected, obtained, 0.001)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/amphorae"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+)
+
+// amphoraProblem returns description of amphora problem or empty string.
+func amphoraProblem(a amphorae.Amphora, deadline time.Time) string {
+	switch {
+	case a.Status == "ERROR":
+		return "error"
+
+	case a.Status == "BOOTING" || strings.HasPrefix(a.Status, "PENDING_"):
+		if a.UpdatedAt.Before(deadline) {
+			return "stuck " + a.Status
+		}
+	}
+
+	return ""
+}
+
+// loadBalancerProblem returns description of load balancer problem or empty string.
+func loadBalancerProblem(lb loadbalancers.LoadBalancer, deadline time.Time) string {
+	switch {
+	case lb.ProvisioningStatus == "ERROR":
+		return "provisioning error"
+
+	case strings.HasPrefix(lb.ProvisioningStatus, "PENDING_") && lb.UpdatedAt.Before(deadline):
+		return "stuck " + lb.ProvisioningStatus
+
+	case lb.OperatingStatus == "ERROR" && lb.AdminStateUp && lb.UpdatedAt.Before(deadline):
+		return "operating error"
+	}
+
+	return ""
+}
+
+// amphoraHeartbeatLost returns description of amphora failed over within the window or empty string.
+//
+// Octavia API does not expose amphora_health, but health manager fails over amphorae with stale heartbeats:
+// the old amphora of the still existing load balancer goes PENDING_DELETE, then DELETED.
+func amphoraHeartbeatLost(a amphorae.Amphora, lbs map[string]loadbalancers.LoadBalancer, since time.Time) string {
+	if (a.Status != "PENDING_DELETE" && a.Status != "DELETED") || a.UpdatedAt.Before(since) {
+		return ""
+	}
+
+	lb, ok := lbs[a.LoadbalancerID]
+	if !ok || lb.ProvisioningStatus == "PENDING_DELETE" || lb.ProvisioningStatus == "DELETED" {
+		return ""
+	}
+
+	if a.Status == "PENDING_DELETE" {
+		return "heartbeat lost, failover in progress"
+	}
+	return "heartbeat lost, failed over"
+}
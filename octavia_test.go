@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/amphorae"
+	"github.com/gophercloud/gophercloud/v2/openstack/loadbalancer/v2/loadbalancers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOctaviaProblems(t *testing.T) {
+	now := time.Now()
+	deadline := now.Add(-10 * time.Minute)
+	old := now.Add(-time.Hour)
+
+	t.Run("amphora", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Equal("", amphoraProblem(amphorae.Amphora{Status: "ALLOCATED", UpdatedAt: old}, deadline))
+		assert.Equal("error", amphoraProblem(amphorae.Amphora{Status: "ERROR", UpdatedAt: now}, deadline))
+		assert.Equal("", amphoraProblem(amphorae.Amphora{Status: "PENDING_CREATE", UpdatedAt: now}, deadline))
+		assert.Equal("stuck BOOTING", amphoraProblem(amphorae.Amphora{Status: "BOOTING", UpdatedAt: old}, deadline))
+	})
+
+	t.Run("heartbeat", func(t *testing.T) {
+		lbs := map[string]loadbalancers.LoadBalancer{
+			"lb1": {ID: "lb1", ProvisioningStatus: "ACTIVE"},
+			"lb2": {ID: "lb2", ProvisioningStatus: "PENDING_DELETE"},
+		}
+		since := now.Add(-24 * time.Hour)
+
+		assert := assert.New(t)
+		assert.Equal("", amphoraHeartbeatLost(amphorae.Amphora{LoadbalancerID: "lb1", Status: "ALLOCATED", UpdatedAt: now}, lbs, since))
+		assert.Equal("heartbeat lost, failed over", amphoraHeartbeatLost(amphorae.Amphora{LoadbalancerID: "lb1", Status: "DELETED", UpdatedAt: old}, lbs, since))
+		assert.Equal("heartbeat lost, failover in progress", amphoraHeartbeatLost(amphorae.Amphora{LoadbalancerID: "lb1", Status: "PENDING_DELETE", UpdatedAt: now}, lbs, since))
+		assert.Equal("", amphoraHeartbeatLost(amphorae.Amphora{LoadbalancerID: "lb1", Status: "DELETED", UpdatedAt: now.Add(-48 * time.Hour)}, lbs, since))
+		assert.Equal("", amphoraHeartbeatLost(amphorae.Amphora{LoadbalancerID: "lb2", Status: "DELETED", UpdatedAt: now}, lbs, since))
+		assert.Equal("", amphoraHeartbeatLost(amphorae.Amphora{LoadbalancerID: "lb3", Status: "DELETED", UpdatedAt: now}, lbs, since))
+	})
+
+	t.Run("loadbalancer", func(t *testing.T) {
+		assert := assert.New(t)
+		assert.Equal("", loadBalancerProblem(loadbalancers.LoadBalancer{ProvisioningStatus: "ACTIVE", OperatingStatus: "ONLINE", AdminStateUp: true, UpdatedAt: old}, deadline))
+		assert.Equal("provisioning error", loadBalancerProblem(loadbalancers.LoadBalancer{ProvisioningStatus: "ERROR", UpdatedAt: now}, deadline))
+		assert.Equal("", loadBalancerProblem(loadbalancers.LoadBalancer{ProvisioningStatus: "PENDING_UPDATE", UpdatedAt: now}, deadline))
+		assert.Equal("stuck PENDING_UPDATE", loadBalancerProblem(loadbalancers.LoadBalancer{ProvisioningStatus: "PENDING_UPDATE", UpdatedAt: old}, deadline))
+		assert.Equal("operating error", loadBalancerProblem(loadbalancers.LoadBalancer{ProvisioningStatus: "ACTIVE", OperatingStatus: "ERROR", AdminStateUp: true, UpdatedAt: old}, deadline))
+		assert.Equal("", loadBalancerProblem(loadbalancers.LoadBalancer{ProvisioningStatus: "ACTIVE", OperatingStatus: "ERROR", AdminStateUp: false, UpdatedAt: old}, deadline))
+	})
+}
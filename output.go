@@ -0,0 +1,423 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+)
+
+// Result collects evaluated records of the check, so they can be rendered as tables or JSON.
+type Result struct {
+	Service  string   `json:"service"`
+	Host     string   `json:"host,omitempty"`
+	State    int      `json:"state"`
+	Status   string   `json:"status"`
+	Summary  string   `json:"summary"`
+	Tables   []*Table `json:"tables"`
+	Messages []string `json:"messages,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+
+	Overrides []Override     `json:"overrides,omitempty"`
+	Config    map[string]any `json:"config,omitempty"`
+
+	// state raised by problems not bound to any record
+	state    int
+	problems []string
+}
+
+// Table records shown in the text output.
+const (
+	ShowAll     = "all"
+	ShowFailing = "failing"
+	ShowNone    = "none"
+)
+
+// summaryHosts limits hosts listed in the summary.
+const summaryHosts = 10
+
+// Table is a titled list of records with the same columns.
+type Table struct {
+	Title   string    `json:"title,omitempty"`
+	Header  table.Row `json:"-"`
+	Records []*Record `json:"records"`
+
+	// HideEmpty is set for problem lists, which are not rendered as text without records.
+	HideEmpty bool `json:"-"`
+}
+
+// Record is an evaluated table row.
+type Record struct {
+	ID      string         `json:"id,omitempty"`
+	Host    string         `json:"host,omitempty"`
+	Binary  string         `json:"binary,omitempty"`
+	Zone    string         `json:"zone,omitempty"`
+	Status  *ServiceStatus `json:"service_status,omitempty"`
+	State   int            `json:"state"`
+	Verdict string         `json:"verdict"`
+	Reason  string         `json:"reason,omitempty"`
+	Fields  map[string]any `json:"fields"`
+
+	values table.Row
+}
+
+// ServiceStatus is set for records of the service lists, e.g. nova services or neutron agents.
+type ServiceStatus struct {
+	Enabled   bool      `json:"enabled"`
+	Up        bool      `json:"up"`
+	Heartbeat time.Time `json:"heartbeat"`
+}
+
+func NewResult(service string) *Result {
+	return &Result{Service: service, Tables: make([]*Table, 0)}
+}
+
+func stateName(state int) string {
+	switch state {
+	case sensu.CheckStateOK:
+		return "OK"
+	case sensu.CheckStateWarning:
+		return "WARNING"
+	case sensu.CheckStateCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// AddTable appends new table with the header columns.
+func (r *Result) AddTable(title string, header ...any) *Table {
+	t := &Table{Title: title, Header: header, Records: make([]*Record, 0)}
+	r.Tables = append(r.Tables, t)
+	return t
+}
+
+// AddProblemTable appends new table, which is not rendered as text without records.
+func (r *Result) AddProblemTable(title string, header ...any) *Table {
+	t := r.AddTable(title, header...)
+	t.HideEmpty = true
+	return t
+}
+
+// Printf adds informational message.
+func (r *Result) Printf(format string, args ...any) {
+	r.Messages = append(r.Messages, fmt.Sprintf(format, args...))
+}
+
+// Fail adds a message about the problem not bound to any record.
+func (r *Result) Fail(state int, format string, args ...any) {
+	r.state = max(r.state, state)
+	r.Printf(format, args...)
+
+	if state != sensu.CheckStateOK {
+		r.problems = append(r.problems, fmt.Sprintf(format, args...))
+	}
+}
+
+// Error records API or configuration error, which makes the state unknown.
+func (r *Result) Error(err error) {
+	r.state = sensu.CheckStateUnknown
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// Evaluate sets the overall state as the worst state of records and problems.
+func (r *Result) Evaluate() int {
+	ret := r.state
+	for _, t := range r.Tables {
+		for _, rec := range t.Records {
+			ret = max(ret, rec.State)
+		}
+	}
+
+	r.State = ret
+	r.Status = stateName(ret)
+	r.Summary = r.summary()
+	return ret
+}
+
+// summary returns one line for notifications, e.g.:
+//
+//	CRITICAL compute: 2/412 down (nova-compute@cmp-17, cmp-88); 3 disabled
+func (r *Result) summary() string {
+	var services, down, disabled int
+	var downHosts, reasons []string
+	var problems int
+
+	lastBinary := ""
+	for _, t := range r.Tables {
+		for _, rec := range t.Records {
+			isDown := false
+			if rec.Status != nil {
+				services++
+
+				switch {
+				case !rec.Status.Enabled:
+					disabled++
+				case !rec.Status.Up:
+					down++
+					isDown = true
+				}
+			}
+
+			if isDown {
+				host := rec.Host
+				if rec.Binary != lastBinary {
+					host = rec.Binary + "@" + rec.Host
+					lastBinary = rec.Binary
+				}
+				downHosts = append(downHosts, host)
+				continue
+			}
+
+			if rec.State != sensu.CheckStateOK {
+				problems++
+				if !slices.Contains(reasons, rec.Reason) {
+					reasons = append(reasons, rec.Reason)
+				}
+			}
+		}
+	}
+
+	parts := make([]string, 0)
+	if services > 0 {
+		if down > 0 {
+			parts = append(parts, fmt.Sprintf("%d/%d down (%s)", down, services, limitList(downHosts, summaryHosts)))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d/%d up", services-disabled, services))
+		}
+
+		if disabled > 0 {
+			parts = append(parts, fmt.Sprintf("%d disabled", disabled))
+		}
+	}
+
+	if problems > 0 {
+		parts = append(parts, fmt.Sprintf("%d problems (%s)", problems, limitList(reasons, summaryHosts)))
+	}
+
+	parts = append(parts, r.problems...)
+
+	if len(r.Errors) > 0 {
+		parts = append(parts, fmt.Sprintf("%d errors (%s)", len(r.Errors), r.Errors[0]))
+	}
+
+	if len(parts) == 0 {
+		records := 0
+		for _, t := range r.Tables {
+			records += len(t.Records)
+		}
+		parts = append(parts, fmt.Sprintf("%d records", records))
+	}
+
+	name := r.Service
+	if r.Host != "" {
+		name += " on " + r.Host
+	}
+
+	return fmt.Sprintf("%s %s: %s", r.Status, name, strings.Join(parts, "; "))
+}
+
+func limitList(items []string, limit int) string {
+	if len(items) <= limit {
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:limit], ", "), len(items)-limit)
+}
+
+// Append adds a record with the column values.
+func (t *Table) Append(values ...any) *Record {
+	rec := &Record{
+		Verdict: stateName(sensu.CheckStateOK),
+		Fields:  make(map[string]any, len(values)),
+		values:  values,
+	}
+
+	for i, v := range values {
+		if i >= len(t.Header) {
+			break
+		}
+		rec.Fields[fmt.Sprint(t.Header[i])] = jsonValue(v)
+	}
+
+	t.Records = append(t.Records, rec)
+	return rec
+}
+
+// Fail raises the record state and adds the reason.
+func (rec *Record) Fail(state int, reason string) {
+	rec.State = max(rec.State, state)
+	rec.Verdict = stateName(rec.State)
+
+	if rec.Reason != "" {
+		reason = rec.Reason + "; " + reason
+	}
+	rec.Reason = reason
+}
+
+func jsonValue(v any) any {
+	switch val := v.(type) {
+	case error:
+		return val.Error()
+	case time.Duration:
+		return val.String()
+	default:
+		return v
+	}
+}
+
+// RenderText writes the summary line, go-pretty tables and messages.
+func (r *Result) RenderText(w io.Writer, show string) {
+	fmt.Fprintln(w, r.Summary)
+
+	for _, t := range r.Tables {
+		records := t.Records
+		if show == ShowFailing {
+			records = slices.DeleteFunc(slices.Clone(records), func(rec *Record) bool {
+				return rec.State == sensu.CheckStateOK
+			})
+		}
+
+		if show == ShowNone || (t.HideEmpty || show == ShowFailing) && len(records) == 0 {
+			continue
+		}
+
+		tw := table.NewWriter()
+		tw.SetOutputMirror(w)
+		if t.Title != "" {
+			tw.SetTitle(t.Title)
+		}
+		tw.AppendHeader(t.Header)
+
+		for _, rec := range records {
+			tw.AppendRow(rec.values)
+		}
+
+		tw.Render()
+	}
+
+	for _, msg := range r.Messages {
+		fmt.Fprintln(w, msg)
+	}
+
+	for _, o := range r.Overrides {
+		fmt.Fprintf(w, "Override from %s: %s=%s\n", o.Source, o.Key, o.Value)
+	}
+
+	if len(r.Config) > 0 {
+		keys := make([]string, 0, len(r.Config))
+		for k := range r.Config {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		tw := table.NewWriter()
+		tw.SetOutputMirror(w)
+		tw.SetTitle("Effective configuration")
+		tw.AppendHeader(table.Row{"Option", "Value"})
+
+		for _, k := range keys {
+			tw.AppendRow(table.Row{k, r.Config[k]})
+		}
+
+		tw.Render()
+	}
+}
+
+// DropRecords removes matching records and returns their count.
+func (r *Result) DropRecords(match func(rec *Record) bool) int {
+	dropped := 0
+	for _, t := range r.Tables {
+		kept := t.Records[:0]
+		for _, rec := range t.Records {
+			if match(rec) {
+				dropped++
+				continue
+			}
+			kept = append(kept, rec)
+		}
+		t.Records = kept
+	}
+	return dropped
+}
+
+// RenderJSON writes the result as indented JSON document.
+func (r *Result) RenderJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// hostMatch compares hosts by full names, or by short names if one of them is unqualified,
+// as OpenStack hosts may be FQDNs and entities may be not.
+func hostMatch(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+
+	sa, _, qa := strings.Cut(a, ".")
+	sb, _, qb := strings.Cut(b, ".")
+	if qa && qb {
+		return false
+	}
+
+	return sa != "" && strings.EqualFold(sa, sb)
+}
+
+// FilterHost returns the result with the host records only.
+//
+// Messages and problems not bound to records are cloud-wide, so they are dropped, API errors are kept.
+// Host problems must be records with the Host set.
+func (r *Result) FilterHost(host string) *Result {
+	ret := NewResult(r.Service)
+	ret.Host = host
+	ret.Errors = r.Errors
+	if len(r.Errors) > 0 {
+		ret.state = sensu.CheckStateUnknown
+	}
+
+	for _, t := range r.Tables {
+		ft := &Table{Title: t.Title, Header: t.Header, Records: make([]*Record, 0), HideEmpty: t.HideEmpty}
+		for _, rec := range t.Records {
+			if rec.Host != "" && hostMatch(rec.Host, host) {
+				ft.Records = append(ft.Records, rec)
+			}
+		}
+
+		if len(ft.Records) > 0 {
+			ret.Tables = append(ret.Tables, ft)
+		}
+	}
+
+	return ret
+}
+
+// Merge appends tables, messages and errors of the other service result.
+func (r *Result) Merge(other *Result) {
+	for _, t := range other.Tables {
+		title := other.Service
+		if t.Title != "" {
+			title += ": " + t.Title
+		}
+
+		mt := *t
+		mt.Title = title
+		r.Tables = append(r.Tables, &mt)
+	}
+
+	for _, msg := range other.Messages {
+		r.Messages = append(r.Messages, other.Service+": "+msg)
+	}
+
+	for _, e := range other.Errors {
+		r.Errors = append(r.Errors, other.Service+": "+e)
+	}
+
+	r.state = max(r.state, other.state)
+	r.problems = append(r.problems, other.problems...)
+}
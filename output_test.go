@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultEvaluate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fill     func(res *Result)
+		expected int
+	}{
+		{"empty", func(res *Result) {}, sensu.CheckStateOK},
+		{"record", func(res *Result) {
+			tb := res.AddTable("", "A")
+			tb.Append("ok")
+			tb.Append("bad").Fail(sensu.CheckStateWarning, "warn")
+		}, sensu.CheckStateWarning},
+		{"problem", func(res *Result) {
+			res.AddTable("", "A").Append("ok")
+			res.Fail(sensu.CheckStateCritical, "no %s", "gateways")
+		}, sensu.CheckStateCritical},
+		{"error", func(res *Result) {
+			res.AddTable("", "A").Append("bad").Fail(sensu.CheckStateCritical, "down")
+			res.Error(errors.New("timeout"))
+		}, sensu.CheckStateUnknown},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := NewResult("compute")
+			tc.fill(res)
+
+			assert.Equal(t, tc.expected, res.Evaluate())
+			assert.Equal(t, tc.expected, res.State)
+			assert.Equal(t, stateName(tc.expected), res.Status)
+		})
+	}
+}
+
+func TestRecordFail(t *testing.T) {
+	res := NewResult("compute")
+	rec := res.AddTable("", "A").Append("x")
+
+	rec.Fail(sensu.CheckStateCritical, "enabled service is down")
+	rec.Fail(sensu.CheckStateWarning, "stale heartbeat")
+
+	assert.Equal(t, sensu.CheckStateCritical, rec.State)
+	assert.Equal(t, "CRITICAL", rec.Verdict)
+	assert.Equal(t, "enabled service is down; stale heartbeat", rec.Reason)
+}
+
+func TestResultRenderJSON(t *testing.T) {
+	res := NewResult("compute")
+	tb := res.AddTable("", "Host", "Latency", "Error")
+	rec := tb.Append("cmp-1", 1500*time.Millisecond, errors.New("refused"))
+	rec.Host = "cmp-1"
+	rec.Fail(sensu.CheckStateCritical, "request failed")
+	res.Error(errors.New("list error"))
+	res.Evaluate()
+
+	var buf bytes.Buffer
+	require.NoError(t, res.RenderJSON(&buf))
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, "compute", doc["service"])
+	assert.Equal(t, "UNKNOWN", doc["status"])
+	assert.Equal(t, []any{"list error"}, doc["errors"])
+
+	records := doc["tables"].([]any)[0].(map[string]any)["records"].([]any)
+	require.Len(t, records, 1)
+
+	r := records[0].(map[string]any)
+	assert.Equal(t, "cmp-1", r["host"])
+	assert.Equal(t, "CRITICAL", r["verdict"])
+	assert.Equal(t, "request failed", r["reason"])
+	assert.Equal(t, map[string]any{"Host": "cmp-1", "Latency": "1.5s", "Error": "refused"}, r["fields"])
+}
+
+func TestResultRenderText(t *testing.T) {
+	res := NewResult("dns")
+	res.AddTable("", "Service").Append("central")
+	res.AddProblemTable("Stuck zones", "ID")
+	res.Printf("done")
+
+	var buf bytes.Buffer
+	res.RenderText(&buf, ShowAll)
+
+	assert.Contains(t, buf.String(), "| central |")
+	assert.NotContains(t, buf.String(), "Stuck zones")
+	assert.Contains(t, buf.String(), "done\n")
+
+	res.AddProblemTable("", "Port")
+	res.AddTable("Stores", "ID")
+
+	buf.Reset()
+	res.RenderText(&buf, ShowAll)
+
+	assert.NotContains(t, buf.String(), "| PORT |")
+	assert.Contains(t, buf.String(), "| ID |")
+}
+
+func TestHostMatch(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"cmp-1", "cmp-1", true},
+		{"cmp-1.example.com", "cmp-1", true},
+		{"CMP-1", "cmp-1.example.com", true},
+		{"cmp-1", "cmp-10", false},
+		{"", "", true},
+		{".a", ".b", false},
+		{"cmp-1.dc1", "cmp-1.dc2", false},
+		{"cmp-1.dc1.example.com", "CMP-1.dc1.example.com", true},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, hostMatch(tc.a, tc.b), "%s %s", tc.a, tc.b)
+	}
+}
+
+func TestResultFilterHostMerge(t *testing.T) {
+	now := time.Now()
+	compute := testMetricsResult(now)
+	compute.Fail(sensu.CheckStateCritical, "cloud-wide problem")
+
+	network := NewResult("network")
+	network.Error(errors.New("timeout"))
+
+	res := NewResult("compute,network")
+	res.Merge(compute.FilterHost("cmp-2.example.com"))
+	res.Merge(network.FilterHost("cmp-2.example.com"))
+
+	require.Len(t, res.Tables, 1)
+	assert.Equal(t, "compute", res.Tables[0].Title)
+	require.Len(t, res.Tables[0].Records, 1)
+	assert.Equal(t, "cmp-2", res.Tables[0].Records[0].Host)
+	assert.Equal(t, []string{"network: timeout"}, res.Errors)
+	assert.Empty(t, res.Messages)
+	assert.Equal(t, sensu.CheckStateUnknown, res.Evaluate())
+
+	res = NewResult("compute")
+	res.Merge(compute.FilterHost("cmp-1"))
+	assert.Equal(t, sensu.CheckStateOK, res.Evaluate())
+}
+
+func TestResultDropRecords(t *testing.T) {
+	res := testMetricsResult(time.Now())
+
+	dropped := res.DropRecords(func(rec *Record) bool {
+		return rec.Host != "cmp-1"
+	})
+
+	assert.Equal(t, 2, dropped)
+	require.Len(t, res.Tables[0].Records, 1)
+	assert.Equal(t, sensu.CheckStateOK, res.Evaluate())
+}
+
+func TestResultSummary(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fill     func(res *Result)
+		expected string
+	}{
+		{"empty", func(res *Result) {}, "OK compute: 0 records"},
+		{"services", func(res *Result) {
+			tb := res.AddTable("", "Host")
+			for i, s := range []struct {
+				binary, host string
+				enabled, up  bool
+			}{
+				{"nova-compute", "cmp-17", true, false},
+				{"nova-compute", "cmp-88", true, false},
+				{"nova-compute", "cmp-90", true, true},
+				{"nova-compute", "cmp-91", false, false},
+				{"nova-scheduler", "ctl-1", true, false},
+			} {
+				rec := tb.Append(i)
+				rec.Binary, rec.Host = s.binary, s.host
+				evaluateService(rec, s.enabled, s.up, time.Time{}, nil)
+			}
+		}, "CRITICAL compute: 3/5 down (nova-compute@cmp-17, cmp-88, nova-scheduler@ctl-1); 1 disabled"},
+		{"all-up", func(res *Result) {
+			rec := res.AddTable("", "Host").Append("cmp-1")
+			rec.Binary, rec.Host = "nova-compute", "cmp-1"
+			evaluateService(rec, true, true, time.Time{}, nil)
+		}, "OK compute: 1/1 up"},
+		{"disabled-reason", func(res *Result) {
+			plugin.CriticalDisabledReason = []string{".*"}
+			t.Cleanup(func() { plugin.CriticalDisabledReason = nil })
+
+			tb := res.AddTable("", "Host")
+			reason := "hardware failure"
+			for _, s := range []struct {
+				binary, host string
+				reason       *string
+			}{
+				{"nova-compute", "cmp-1", &reason},
+				{"neutron-ovs-agent", "cmp-2", nil},
+			} {
+				rec := tb.Append(s.host)
+				rec.Binary, rec.Host = s.binary, s.host
+				evaluateService(rec, false, true, time.Time{}, s.reason)
+			}
+		}, "CRITICAL compute: 0/2 up; 2 disabled; 1 problems (disabled with critical reason)"},
+		{"problems", func(res *Result) {
+			tb := res.AddProblemTable("Stuck zones", "ID")
+			tb.Append("z1").Fail(sensu.CheckStateCritical, "zone stuck in ERROR")
+			tb.Append("z2").Fail(sensu.CheckStateCritical, "zone stuck in ERROR")
+			res.Fail(sensu.CheckStateCritical, "OVN gateway chassis alive: %d of %d", 1, 3)
+			res.Printf("informational")
+			res.Error(errors.New("timeout"))
+		}, "UNKNOWN compute: 2 problems (zone stuck in ERROR); OVN gateway chassis alive: 1 of 3; 1 errors (timeout)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := NewResult("compute")
+			tc.fill(res)
+			res.Evaluate()
+
+			assert.Equal(t, tc.expected, res.Summary)
+		})
+	}
+
+	assert.Equal(t, "a, b and 2 more", limitList([]string{"a", "b", "c", "d"}, 2))
+}
+
+func TestResultRenderTextShow(t *testing.T) {
+	res := testMetricsResult(time.Now())
+
+	testCases := []struct {
+		show        string
+		contains    []string
+		notContains []string
+	}{
+		{ShowAll, []string{"| cmp-1 |", "| cmp-2 |"}, nil},
+		{ShowFailing, []string{"| cmp-2 |"}, []string{"| cmp-1 |", "| cmp-3 |"}},
+		{ShowNone, nil, []string{"cmp-1 |", "cmp-2 |", "+"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.show, func(t *testing.T) {
+			var buf bytes.Buffer
+			res.RenderText(&buf, tc.show)
+
+			out := buf.String()
+			assert.True(t, strings.HasPrefix(out, "CRITICAL compute: 1/3 down (nova-compute@cmp-2); 1 disabled\n"), out)
+			for _, s := range tc.contains {
+				assert.Contains(t, out, s)
+			}
+			for _, s := range tc.notContains {
+				assert.NotContains(t, out, s)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+)
+
+// Override is an option value set from the event labels or annotations.
+type Override struct {
+	Source string `json:"source"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+}
+
+// overridablePaths are the option paths allowed in labels and annotations layering.
+//
+// Only ignore lists, thresholds and reason regexps, so entity owners cannot change credentials,
+// start the exporter, write files, send events elsewhere or boot canary instances.
+// Such options have no keyspace path at all, otherwise the SDK would apply their annotations.
+var overridablePaths = []string{
+	"critical_disabled_reason",
+	"ignore_hosts",
+	"exclude_binaries",
+	"ignore_device_owner",
+	"dhcp_agents_per_network",
+	"ovn_min_gateways",
+	"grace_period",
+	"time_window",
+	"image_failed_imports",
+	"ip_warning",
+	"ip_critical",
+	"metric_max_measures",
+	"metric_max_metrics",
+	"metric_min_processors",
+	"latency_warning",
+	"latency_critical",
+	"interface_latency_warning",
+	"interface_latency_critical",
+	"cert_warning_days",
+	"cert_critical_days",
+}
+
+// optionPath returns the keyspace path, the argument name and the current value of the option.
+func optionPath(opt sensu.ConfigOption) (string, string, any) {
+	switch o := opt.(type) {
+	case *sensu.PluginConfigOption[string]:
+		return o.Path, o.Argument, *o.Value
+	case *sensu.PluginConfigOption[int]:
+		return o.Path, o.Argument, *o.Value
+	case *sensu.PluginConfigOption[bool]:
+		return o.Path, o.Argument, *o.Value
+	case *sensu.PluginConfigOption[float64]:
+		return o.Path, o.Argument, *o.Value
+	case *sensu.SlicePluginConfigOption[string]:
+		return o.Path, o.Argument, *o.Value
+	case *sensu.MapPluginConfigOption[string]:
+		return o.Path, o.Argument, *o.Value
+	default:
+		return "", "", nil
+	}
+}
+
+// applyOverrides sets options from labels and annotations under the plugin keyspace.
+//
+// The more specific source wins: check labels, check annotations, entity labels, entity annotations.
+// So the entity can tune the shared check definition, unlike the SDK, which prefers check annotations.
+//
+// Only the allowed paths are layered. Annotations of other options are left as the SDK applied them,
+// other keys under the keyspace are ignored with a warning.
+func applyOverrides(keyspace string, opts []sensu.ConfigOption, allowed []string, event *corev2.Event, warn io.Writer) ([]Override, error) {
+	if event == nil || keyspace == "" {
+		return nil, nil
+	}
+
+	type source struct {
+		name       string
+		values     map[string]string
+		annotation bool
+	}
+
+	sources := make([]source, 0, 4)
+	if event.Check != nil {
+		sources = append(sources,
+			source{"check label", event.Check.Labels, false},
+			source{"check annotation", event.Check.Annotations, true},
+		)
+	}
+	if event.Entity != nil {
+		sources = append(sources,
+			source{"entity label", event.Entity.Labels, false},
+			source{"entity annotation", event.Entity.Annotations, true},
+		)
+	}
+
+	sdkPaths := make([]string, 0, len(opts))
+	for _, opt := range opts {
+		p, _, _ := optionPath(opt)
+		if p != "" {
+			sdkPaths = append(sdkPaths, p)
+		}
+	}
+
+	for _, src := range sources {
+		keys := make([]string, 0, len(src.values))
+		for key := range src.values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			p, ok := strings.CutPrefix(key, keyspace+"/")
+			if !ok || src.values[key] == "" || slices.Contains(allowed, p) || (src.annotation && slices.Contains(sdkPaths, p)) {
+				continue
+			}
+
+			fmt.Fprintf(warn, "Ignored %s %s: option cannot be overridden\n", src.name, key)
+		}
+	}
+
+	overrides := make(map[string]Override)
+	for _, opt := range opts {
+		p, _, _ := optionPath(opt)
+		if p == "" || !slices.Contains(allowed, p) {
+			continue
+		}
+
+		key := path.Join(keyspace, p)
+		for _, src := range sources {
+			value, ok := src.values[key]
+			if !ok || value == "" {
+				continue
+			}
+
+			// map SetValue unmarshals into the current map, so the override would merge with it
+			m, ok := opt.(*sensu.MapPluginConfigOption[string])
+			if ok {
+				*m.Value = nil
+			}
+
+			err := opt.SetValue(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", src.name, key, err)
+			}
+
+			overrides[key] = Override{Source: src.name, Key: key, Value: value}
+		}
+	}
+
+	ret := make([]Override, 0, len(overrides))
+	for _, o := range overrides {
+		ret = append(ret, o)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Key < ret[j].Key
+	})
+
+	return ret, nil
+}
+
+// effectiveConfig returns the values of all options by the keyspace path or the argument name.
+func effectiveConfig(opts []sensu.ConfigOption) map[string]any {
+	ret := make(map[string]any, len(opts))
+	for _, opt := range opts {
+		p, arg, value := optionPath(opt)
+		if p == "" {
+			p = strings.ReplaceAll(arg, "-", "_")
+		}
+		if p != "" {
+			ret[p] = value
+		}
+	}
+	return ret
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	const keyspace = "sensu.io/plugins/test/config"
+
+	var (
+		ipWarning   float64
+		ignoreHosts []string
+		service     string
+		listen      string
+		textfileDir string
+		cloud       string
+	)
+
+	opts := []sensu.ConfigOption{
+		&sensu.PluginConfigOption[float64]{Path: "ip_warning", Argument: "ip-warning", Value: &ipWarning},
+		&sensu.SlicePluginConfigOption[string]{Path: "ignore_hosts", Argument: "ignore-hosts", Value: &ignoreHosts},
+		&sensu.PluginConfigOption[string]{Argument: "no-path", Value: &service},
+		&sensu.PluginConfigOption[string]{Argument: "listen", Value: &listen},
+		&sensu.PluginConfigOption[string]{Argument: "textfile-dir", Value: &textfileDir},
+		&sensu.PluginConfigOption[string]{Path: "cloud", Argument: "cloud", Value: &cloud},
+	}
+	allowed := []string{"ip_warning", "ignore_hosts"}
+
+	event := corev2.FixtureEvent("cmp-1", "openstack")
+	event.Check.Labels = map[string]string{keyspace + "/ip_warning": "70"}
+	event.Check.Annotations = map[string]string{keyspace + "/ip_warning": "75", keyspace + "/ignore_hosts": `["cmp-9"]`}
+	event.Entity.Annotations = map[string]string{keyspace + "/ip_warning": "80", "no-path": "x"}
+
+	overrides, err := applyOverrides(keyspace, opts, allowed, event, io.Discard)
+	require.NoError(t, err)
+
+	assert.Equal(t, 80.0, ipWarning)
+	assert.Equal(t, []string{"cmp-9"}, ignoreHosts)
+	assert.Equal(t, "", service)
+	assert.Equal(t, []Override{
+		{Source: "check annotation", Key: keyspace + "/ignore_hosts", Value: `["cmp-9"]`},
+		{Source: "entity annotation", Key: keyspace + "/ip_warning", Value: "80"},
+	}, overrides)
+
+	assert.Equal(t, map[string]any{
+		"ip_warning":   80.0,
+		"ignore_hosts": []string{"cmp-9"},
+		"no_path":      "",
+		"listen":       "",
+		"textfile_dir": "",
+		"cloud":        "",
+	}, effectiveConfig(opts))
+
+	event.Entity.Labels = map[string]string{keyspace + "/ip_warning": "many"}
+	_, err = applyOverrides(keyspace, opts, allowed, event, io.Discard)
+	assert.ErrorContains(t, err, "entity label")
+
+	overrides, err = applyOverrides(keyspace, opts, allowed, nil, io.Discard)
+	assert.NoError(t, err)
+	assert.Empty(t, overrides)
+
+	var warn bytes.Buffer
+	event = corev2.FixtureEvent("cmp-1", "openstack")
+	event.Check.Annotations = map[string]string{keyspace + "/listen": ":9100", keyspace + "/cloud": "other"}
+	event.Entity.Labels = map[string]string{keyspace + "/textfile_dir": "/tmp", keyspace + "/cloud": "other", keyspace + "/ip_warning": "90"}
+
+	overrides, err = applyOverrides(keyspace, opts, allowed, event, &warn)
+	require.NoError(t, err)
+	assert.Equal(t, []Override{{Source: "entity label", Key: keyspace + "/ip_warning", Value: "90"}}, overrides)
+	// cloud annotation is applied by the SDK as before
+	assert.Equal(t, "Ignored check annotation "+keyspace+"/listen: option cannot be overridden\n"+
+		"Ignored entity label "+keyspace+"/cloud: option cannot be overridden\n"+
+		"Ignored entity label "+keyspace+"/textfile_dir: option cannot be overridden\n", warn.String())
+	assert.Empty(t, listen)
+	assert.Empty(t, textfileDir)
+	assert.Empty(t, cloud)
+
+	for _, p := range []string{"listen", "textfile_dir", "agent_api", "cloud", "canary_image", "timeout"} {
+		assert.NotContains(t, overridablePaths, p)
+	}
+}
+
+func TestApplyOverridesMap(t *testing.T) {
+	const keyspace = "sensu.io/plugins/test/config"
+
+	latency := map[string]string{"public": "1s", "internal": "500ms"}
+	opts := []sensu.ConfigOption{
+		&sensu.MapPluginConfigOption[string]{Path: "interface_latency_warning", Argument: "interface-latency-warning", Value: &latency},
+	}
+
+	event := corev2.FixtureEvent("cmp-1", "openstack")
+	event.Entity.Annotations = map[string]string{keyspace + "/interface_latency_warning": `{"public": "2s"}`}
+
+	_, err := applyOverrides(keyspace, opts, []string{"interface_latency_warning"}, event, io.Discard)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"public": "2s"}, latency)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/containers"
+	"github.com/gophercloud/gophercloud/v2/openstack/objectstorage/v1/objects"
+)
+
+// swiftCleanupTimeout limits the probe object delete, which is done even if the check deadline exceeded.
+const swiftCleanupTimeout = 30 * time.Second
+
+// swiftProxyURL strips account path from the object-store endpoint, e.g.:
+//
+//	https://swift.example.com:8080/v1/AUTH_xxx -> https://swift.example.com:8080/
+func swiftProxyURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	if i := strings.Index(u.Path, "/v1"); i >= 0 {
+		u.Path = u.Path[:i]
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/"
+	u.RawQuery = ""
+
+	return u.String(), nil
+}
+
+// swiftProbe requests unauthenticated proxy middleware endpoint and returns response latency.
+func swiftProbe(ctx context.Context, cli *http.Client, proxyURL, path string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, proxyURL+path, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	resp, err := cli.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(io.Discard, resp.Body)
+	latency := time.Since(start)
+	if err != nil {
+		return latency, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return latency, fmt.Errorf("%s: unexpected status: %s", path, resp.Status)
+	}
+
+	return latency, nil
+}
+
+// swiftRoundTrip uploads, downloads and deletes small object, returns step latencies.
+//
+// The object is deleted whatever happens after the upload, so failing runs do not fill the container.
+func swiftRoundTrip(ctx context.Context, cli *gophercloud.ServiceClient, container, object string) (ret map[string]time.Duration, err error) {
+	ret = make(map[string]time.Duration)
+	payload := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+
+	_, err = containers.Create(ctx, cli, container, nil).Extract()
+	if err != nil {
+		return ret, fmt.Errorf("container create: %w", err)
+	}
+
+	start := time.Now()
+	_, err = objects.Create(ctx, cli, container, object, objects.CreateOpts{
+		Content:     bytes.NewReader(payload),
+		ContentType: "text/plain",
+	}).Extract()
+	ret["PUT"] = time.Since(start)
+	if err != nil {
+		return ret, fmt.Errorf("PUT: %w", err)
+	}
+
+	defer func() {
+		cleanupCtx, cf := context.WithTimeout(context.Background(), swiftCleanupTimeout)
+		defer cf()
+
+		start := time.Now()
+		_, derr := objects.Delete(cleanupCtx, cli, container, object, nil).Extract()
+		ret["DELETE"] = time.Since(start)
+		if derr != nil {
+			err = errors.Join(err, fmt.Errorf("DELETE: %w", derr))
+		}
+	}()
+
+	start = time.Now()
+	res := objects.Download(ctx, cli, container, object, nil)
+	content, err := res.ExtractContent()
+	ret["GET"] = time.Since(start)
+	if err != nil {
+		return ret, fmt.Errorf("GET: %w", err)
+	}
+	if !bytes.Equal(content, payload) {
+		return ret, fmt.Errorf("GET: content mismatch")
+	}
+
+	return ret, nil
+}
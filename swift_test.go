@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gophercloud/gophercloud/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSwiftProxyURL(t *testing.T) {
+	testCases := []struct {
+		endpoint string
+		expected string
+	}{
+		{"https://swift.example.com:8080/v1/AUTH_0123", "https://swift.example.com:8080/"},
+		{"https://api.example.com/object-store/v1/AUTH_0123", "https://api.example.com/object-store/"},
+		{"http://10.0.0.1:8080", "http://10.0.0.1:8080/"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.endpoint, func(t *testing.T) {
+			obtained, err := swiftProxyURL(tc.endpoint)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, obtained)
+		})
+	}
+}
+
+func TestSwiftRoundTripDelete(t *testing.T) {
+	testCases := []struct {
+		name     string
+		deadline bool
+		err      string
+	}{
+		{"mismatch", false, "content mismatch"},
+		{"deadline", true, "context canceled"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			deleted := 0
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /sensu-check", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			})
+			mux.HandleFunc("PUT /sensu-check/probe", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			})
+			mux.HandleFunc("GET /sensu-check/probe", func(w http.ResponseWriter, r *http.Request) {
+				if tc.deadline {
+					cancel()
+					<-r.Context().Done()
+					return
+				}
+				w.Write([]byte("corrupted"))
+			})
+			mux.HandleFunc("DELETE /sensu-check/probe", func(w http.ResponseWriter, r *http.Request) {
+				deleted++
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			cli := &gophercloud.ServiceClient{ProviderClient: &gophercloud.ProviderClient{TokenID: "token"}, Endpoint: srv.URL + "/"}
+
+			latencies, err := swiftRoundTrip(ctx, cli, "sensu-check", "probe")
+			assert.ErrorContains(t, err, tc.err)
+			assert.NotContains(t, err.Error(), "DELETE")
+			assert.Equal(t, 1, deleted)
+			assert.Contains(t, latencies, "PUT")
+			assert.Contains(t, latencies, "GET")
+			assert.Contains(t, latencies, "DELETE")
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev2 "github.com/sensu/core/v2"
+	"github.com/sensu/sensu-plugin-sdk/sensu/metric"
+)
+
+const lastSuccessMetric = "openstack_check_last_success_timestamp_seconds"
+
+var promLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeTextfile atomically replaces the node_exporter textfile collector file of the service.
+//
+// Last success timestamp is kept from the previous file if the check got API errors.
+func writeTextfile(dir string, res *Result, now time.Time) error {
+	path := filepath.Join(dir, "openstack_"+res.Service+".prom")
+
+	lastSuccess := float64(now.Unix())
+	if len(res.Errors) > 0 {
+		lastSuccess = readTextfileValue(path, lastSuccessMetric)
+	}
+
+	points := resultMetrics(res, now)
+	points = append(points, newMetricPoint(lastSuccessMetric, lastSuccess, now.Unix(), "service", res.Service))
+
+	f, err := os.CreateTemp(dir, ".openstack_"+res.Service+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	err = writeGauges(f, points)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Chmod(0o644)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
+
+// writeGauges writes points in the Prometheus text format without timestamps,
+// which textfile collector rejects and the exporter cached samples must not have.
+func writeGauges(w io.Writer, points metric.Points) error {
+	bw := bufio.NewWriter(w)
+	written := make(map[string]bool)
+
+	// samples of the metric must be grouped
+	points = slices.Clone(points)
+	slices.SortStableFunc(points, func(a, b *corev2.MetricPoint) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	for _, p := range points {
+		if !written[p.Name] {
+			written[p.Name] = true
+			fmt.Fprintf(bw, "# TYPE %s gauge\n", p.Name)
+		}
+
+		labels := make([]string, 0, len(p.Tags))
+		for _, tag := range p.Tags {
+			labels = append(labels, fmt.Sprintf(`%s="%s"`, tag.Name, promLabelEscaper.Replace(tag.Value)))
+		}
+
+		fmt.Fprintf(bw, "%s{%s} %s\n", p.Name, strings.Join(labels, ","), formatMetricValue(p.Value))
+	}
+
+	return bw.Flush()
+}
+
+// readTextfileValue returns the first sample value of the metric or 0 if the file or metric is absent.
+func readTextfileValue(path, name string) float64 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, name+"{") && !strings.HasPrefix(line, name+" ") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		v, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+		if err == nil {
+			return v
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTextfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "openstack_compute.prom")
+
+	first := time.Unix(1700000000, 0)
+	require.NoError(t, writeTextfile(dir, testMetricsResult(first), first))
+
+	buf, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	content := string(buf)
+	assert.Equal(t, 1, strings.Count(content, "# TYPE openstack_services gauge\n"))
+	assert.Contains(t, content, `openstack_check_state{service="compute"} 2`+"\n")
+	assert.Contains(t, content, `openstack_check_last_success_timestamp_seconds{service="compute"} 1700000000`+"\n")
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), fi.Mode().Perm())
+
+	// API error keeps last success
+	second := first.Add(time.Minute)
+	res := NewResult("compute")
+	res.Error(errors.New("timeout"))
+	res.Evaluate()
+	require.NoError(t, writeTextfile(dir, res, second))
+
+	assert.Equal(t, 1700000000.0, readTextfileValue(path, lastSuccessMetric))
+	assert.Equal(t, 3.0, readTextfileValue(path, "openstack_check_state"))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file left")
+}
+
+func TestReadTextfileValue(t *testing.T) {
+	assert.Equal(t, 0.0, readTextfileValue(filepath.Join(t.TempDir(), "absent.prom"), lastSuccessMetric))
+}